@@ -0,0 +1,92 @@
+package expirecache
+
+import "sync/atomic"
+
+// sketchDepth is the number of count-min sketch rows
+const sketchDepth = 4
+
+// frequencySketch is a count-min sketch estimating the keys lookup frequency, safe for use under the read lock
+type frequencySketch[K comparable] struct {
+	// updated atomically, keep first for 64-bit alignment
+	additions uint64
+
+	hash     func(k K) uint64
+	counters []uint32 // sketchDepth rows of width counters
+	width    uint64
+	// counters are halved every resetAt additions, so the old popularity fades out
+	resetAt uint64
+}
+
+// SetAdmission enables the TinyLFU-style admission filter: when a new item needs evictions to fit,
+// it's stored only if its estimated lookup frequency (Get, GetOrSet) is higher than the first victim's one.
+// This improves the hit rate for skewed workloads, keeping popular items from being evicted by one-off items.
+// A rejected item isn't stored: TrySet returns false, SetCtx, SetTier and SetAuto return ErrNotAdmitted.
+// hash must distribute the keys well, width is the number of counters per sketch row
+// (about the expected number of items). hash is invoked under the write lock by the stores (and with no lock held
+// by the lookups), so it must not call back into the cache. A nil hash disables the filter.
+func (ec *Cache[K, T]) SetAdmission(hash func(k K) uint64, width int) {
+	ec.Lock()
+	if hash == nil {
+		ec.admission = nil
+	} else {
+		ec.admission = newFrequencySketch(hash, width)
+	}
+	ec.Unlock()
+}
+
+func newFrequencySketch[K comparable](hash func(k K) uint64, width int) *frequencySketch[K] {
+	w := uint64(16)
+	for w < uint64(width) {
+		w <<= 1
+	}
+	return &frequencySketch[K]{
+		hash:     hash,
+		counters: make([]uint32, sketchDepth*w),
+		width:    w,
+		resetAt:  10 * w,
+	}
+}
+
+// mix64 is the splitmix64 finalizer, for spreading weak user hashes
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+func (s *frequencySketch[K]) index(h uint64, row int) uint64 {
+	h2 := h>>32 | 1
+	return uint64(row)*s.width + (h+uint64(row)*h2)&(s.width-1)
+}
+
+func (s *frequencySketch[K]) increment(k K) {
+	h := mix64(s.hash(k))
+	for row := 0; row < sketchDepth; row++ {
+		atomic.AddUint32(&s.counters[s.index(h, row)], 1)
+	}
+	if atomic.AddUint64(&s.additions, 1)%s.resetAt == 0 {
+		// concurrent increments may be lost, it's only an estimation
+		for i := range s.counters {
+			atomic.StoreUint32(&s.counters[i], atomic.LoadUint32(&s.counters[i])/2)
+		}
+	}
+}
+
+func (s *frequencySketch[K]) estimate(k K) uint32 {
+	h := mix64(s.hash(k))
+	min := atomic.LoadUint32(&s.counters[s.index(h, 0)])
+	for row := 1; row < sketchDepth; row++ {
+		if n := atomic.LoadUint32(&s.counters[s.index(h, row)]); n < min {
+			min = n
+		}
+	}
+	return min
+}
+
+// admit checks if the new item k is worth evicting the victim
+func (s *frequencySketch[K]) admit(k, victim K) bool {
+	return s.estimate(k) > s.estimate(victim)
+}
@@ -0,0 +1,112 @@
+package expirecache
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func zipfHitRate(admission bool) float64 {
+	c := New[uint64, uint64](100)
+	if admission {
+		c.SetAdmission(func(k uint64) uint64 { return k }, 100)
+	}
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 10000)
+
+	const (
+		warmup   = 10000
+		accesses = 100000
+	)
+	var hits int
+	for n := 0; n < warmup+accesses; n++ {
+		k := zipf.Uint64()
+		if _, ok := c.Get(k); ok {
+			if n >= warmup {
+				hits++
+			}
+		} else {
+			c.Set(k, k, 1, 3600)
+		}
+	}
+	return float64(hits) / accesses
+}
+
+func TestCacheAdmission(t *testing.T) {
+	without := zipfHitRate(false)
+	with := zipfHitRate(true)
+	t.Logf("zipf hit rate: %.3f without admission, %.3f with admission", without, with)
+	if with <= without*1.1 {
+		t.Errorf("hit rate with admission %.3f should be better than without %.3f", with, without)
+	}
+}
+
+func TestCacheAdmissionReject(t *testing.T) {
+	c := New[uint64, uint64](2)
+	c.SetAdmission(func(k uint64) uint64 { return k }, 16)
+
+	c.Set(1, 1, 1, 3600)
+	c.Set(2, 2, 1, 3600)
+	for i := 0; i < 5; i++ {
+		c.Get(1)
+		c.Get(2)
+	}
+
+	// one-off key isn't admitted
+	c.Get(3)
+	c.Set(3, 3, 1, 3600)
+	if _, ok := c.Get(3); ok {
+		t.Errorf("cold key 3 should not be admitted")
+	}
+	if c.Items() != 2 || c.Size() != 2 {
+		t.Errorf("items, size = %d, %d, want 2, 2", c.Items(), c.Size())
+	}
+
+	// popular key is admitted
+	for i := 0; i < 10; i++ {
+		c.Get(4)
+	}
+	c.Set(4, 4, 1, 3600)
+	if _, ok := c.Get(4); !ok {
+		t.Errorf("hot key 4 should be admitted")
+	}
+	if c.Items() != 2 || c.Size() != 2 {
+		t.Errorf("items, size = %d, %d, want 2, 2", c.Items(), c.Size())
+	}
+}
+
+func TestCacheAdmissionRejectResult(t *testing.T) {
+	c := New[uint64, uint64](2)
+	c.SetAdmission(func(k uint64) uint64 { return k }, 16)
+	c.Set(1, 1, 1, 3600)
+	c.Set(2, 2, 1, 3600)
+	for i := 0; i < 5; i++ {
+		c.Get(1)
+		c.Get(2)
+	}
+
+	if c.TrySet(3, 3, 1, 3600, c.DependsOn(1)) {
+		t.Errorf("cache.TrySet(3) of the rejected item = true, want false")
+	}
+	// the rejected item isn't linked to its dependencies
+	if _, ok := c.dependents[1]; ok {
+		t.Errorf("the dependents of 1 = %v, want none", c.dependents[1])
+	}
+	if err := c.SetCtx(context.Background(), 3, 3, 1, 3600); err != ErrNotAdmitted {
+		t.Errorf("cache.SetCtx(3) error = %v, want %v", err, ErrNotAdmitted)
+	}
+	if err := c.SetAuto(3, 3, 3600); err != ErrNotAdmitted {
+		t.Errorf("cache.SetAuto(3) error = %v, want %v", err, ErrNotAdmitted)
+	}
+	c.RegisterTier("hour", time.Hour)
+	if err := c.SetTier(3, 3, 1, "hour"); err != ErrNotAdmitted {
+		t.Errorf("cache.SetTier(3) error = %v, want %v", err, ErrNotAdmitted)
+	}
+	if _, ok := c.Inspect(3); ok {
+		t.Errorf("cache.Inspect(3) of the rejected item should miss")
+	}
+	// overwrites are always admitted
+	if !c.TrySet(1, 10, 1, 3600) {
+		t.Errorf("cache.TrySet(1) of the overwrite = false, want true")
+	}
+}
@@ -0,0 +1,38 @@
+package expirecache
+
+// Mutation is a Set or a Delete (if Delete is true) of an item applied by Apply
+type Mutation[K comparable, T any] struct {
+	Key    K
+	Delete bool
+
+	// the Set arguments
+	Value  T
+	Size   uint64
+	Expire int32 // in seconds
+	Opts   []SetOption
+}
+
+// Apply applies the mutations in order under a single write lock, so no reader observes a partial application.
+// A Delete removes the items depending on the removed one too, like Cache.Delete. A Set over the Set rate limit
+// (see WithSetRateLimit) is skipped. It's a no-op if the cache is read-only.
+func (ec *Cache[K, T]) Apply(ops []Mutation[K, T]) {
+	ec.Lock()
+	held := ec.lockHeld()
+	if ec.readOnly {
+		ec.Unlock()
+		return
+	}
+	for _, op := range ops {
+		if op.Delete {
+			ec.invalidateDependents(op.Key)
+			if v, ok := ec.get(op.Key); ok {
+				ec.removeAt(v.keyIdx)
+			}
+		} else if ec.allowSet() {
+			ec.actualSet(op.Key, op.Value, op.Size, ec.ttl(op.Expire), op.Opts...)
+		}
+	}
+	hold := ec.holdTime(held)
+	ec.unlock()
+	ec.slowOp("apply", hold)
+}
@@ -0,0 +1,81 @@
+package expirecache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCacheApply(t *testing.T) {
+	c := New[string, int](0)
+	c.Set("a", 0, 1, 60)
+	c.Set("b", 0, 1, 60)
+	c.Set("c", 0, 1, 60)
+	c.Set("dep", 0, 1, 60, c.DependsOn("c"))
+
+	c.Apply([]Mutation[string, int]{
+		{Key: "a", Value: 1, Size: 2, Expire: 60},
+		{Key: "c", Delete: true},
+		{Key: "d", Value: 1, Size: 2, Expire: 60},
+		{Key: "d", Delete: true},
+		{Key: "e", Value: 1, Size: 2, Expire: 60, Opts: []SetOption{Sticky()}},
+		{Key: "missing", Delete: true},
+	})
+	for k, want := range map[string]int{"a": 1, "b": 0, "e": 1} {
+		if v, ok := c.Get(k); !ok || v != want {
+			t.Errorf("cache.Get(%s) = (%d, %v), want (%d, true)", k, v, ok, want)
+		}
+	}
+	for _, k := range []string{"c", "d", "dep", "missing"} {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("cache.Get(%s) should miss", k)
+		}
+	}
+	if c.Items() != 3 || c.Size() != 5 {
+		t.Errorf("items = %d, size %d, want 3, 5", c.Items(), c.Size())
+	}
+	c.Clear()
+	if _, ok := c.Get("e"); !ok {
+		t.Errorf("cache.Get(e) of the sticky item should be present after Clear")
+	}
+
+	c.SetReadOnly(true)
+	c.Apply([]Mutation[string, int]{{Key: "e", Delete: true}})
+	if _, ok := c.Get("e"); !ok {
+		t.Errorf("cache.Get(e) should be present after Apply to the read-only cache")
+	}
+}
+
+func TestCacheApplyAtomic(t *testing.T) {
+	c := New[string, int](0)
+	c.Set("a", 0, 1, 60)
+	c.Set("b", 0, 1, 60)
+
+	const batches = 1000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= batches; i++ {
+			c.Apply([]Mutation[string, int]{
+				{Key: "a", Value: i, Size: 1, Expire: 60},
+				{Key: "b", Delete: true},
+				{Key: "b", Value: i, Size: 1, Expire: 60},
+			})
+		}
+	}()
+
+	// a and b are always updated together
+	for done := false; !done; {
+		items := make(map[string]int)
+		for _, e := range c.Entries() {
+			items[e.Key] = e.Value
+		}
+		va, okA := items["a"]
+		vb, okB := items["b"]
+		if !okA || !okB || va != vb {
+			t.Fatalf("cache.Entries() = %v, want a and b equal", items)
+		}
+		done = va == batches
+	}
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+package expirecache
+
+// BatchSource loads several items with a single call, e.g. a multi-get from a backend.
+// Keys absent from the result are missing from the backend.
+type BatchSource[K comparable, T any] interface {
+	LoadMany(keys []K) (map[K]T, error)
+}
+
+// SetBatchSource sets the source of GetManyReadThrough, the loaded items are stored with the expiration time in seconds
+// and the size estimated like by SetAuto. Pass nil to disable.
+func (ec *Cache[K, T]) SetBatchSource(src BatchSource[K, T], expire int32) {
+	ec.Lock()
+	ec.batchSource = src
+	ec.batchExpire = expire
+	ec.Unlock()
+}
+
+// GetManyReadThrough returns the items for the keys, loading all the missing ones with a single LoadMany call
+// of the batch source (see SetBatchSource) with no lock held, and storing the loaded items.
+// Keys missing from both the cache and the source are absent from the result.
+// On a LoadMany error, the items found in the cache are returned with the error.
+// ErrNoLoader is returned with them if there are missing keys, but no batch source.
+func (ec *Cache[K, T]) GetManyReadThrough(keys []K) (map[K]T, error) {
+	items := make(map[K]T, len(keys))
+	seen := make(map[K]struct{}, len(keys))
+	var missing []K
+	for _, k := range keys {
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if v, ok := ec.Get(k); ok {
+			items[k] = v
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	ec.RLock()
+	src, expire, sizeFunc, closed := ec.batchSource, ec.batchExpire, ec.sizeFunc, ec.closed
+	ec.RUnlock()
+	if closed {
+		return items, ErrClosed
+	}
+	if src == nil {
+		return items, ErrNoLoader
+	}
+	loaded, err := src.LoadMany(missing)
+	if err != nil {
+		return items, err
+	}
+
+	sizes := make(map[K]uint64, len(loaded))
+	for _, k := range missing {
+		v, ok := loaded[k]
+		if !ok {
+			continue
+		}
+		items[k] = v
+		// an item which size can't be estimated is returned, but not stored
+		if size, err := autoSize(k, v, sizeFunc); err == nil {
+			sizes[k] = size
+		}
+	}
+	ec.Lock()
+	if !ec.readOnly {
+		for k, size := range sizes {
+			if ec.allowSet() {
+				ec.actualSet(k, items[k], size, ec.ttl(expire))
+			}
+		}
+	}
+	ec.unlock()
+	return items, nil
+}
@@ -0,0 +1,70 @@
+package expirecache
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type fakeBatchSource struct {
+	data  map[string]string
+	calls [][]string
+	err   error
+}
+
+func (s *fakeBatchSource) LoadMany(keys []string) (map[string]string, error) {
+	s.calls = append(s.calls, append([]string(nil), keys...))
+	if s.err != nil {
+		return nil, s.err
+	}
+	res := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := s.data[k]; ok {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
+
+func TestCacheGetManyReadThrough(t *testing.T) {
+	c := New[string, string](0)
+	src := &fakeBatchSource{data: map[string]string{"a": "1", "b": "2", "c": "3"}}
+
+	c.Set("cached", "0", 1, 60)
+	if _, err := c.GetManyReadThrough([]string{"cached", "a"}); err != ErrNoLoader {
+		t.Errorf("cache.GetManyReadThrough() without a source error = %v, want ErrNoLoader", err)
+	}
+
+	c.SetBatchSource(src, 60)
+	c.SetSizeFunc(func(v string) uint64 { return uint64(len(v)) })
+
+	got, err := c.GetManyReadThrough([]string{"cached", "a", "b", "a", "missing"})
+	want := map[string]string{"cached": "0", "a": "1", "b": "2"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.GetManyReadThrough() = (%v, %v), want (%v, nil)", got, err, want)
+	}
+	if len(src.calls) != 1 {
+		t.Fatalf("LoadMany calls = %v, want 1", src.calls)
+	}
+	sort.Strings(src.calls[0])
+	if !reflect.DeepEqual(src.calls[0], []string{"a", "b", "missing"}) {
+		t.Errorf("LoadMany keys = %v, want all misses", src.calls[0])
+	}
+	for _, k := range []string{"a", "b"} {
+		if v, ok := c.Get(k); !ok || v != want[k] {
+			t.Errorf("cache.Get(%s) = (%q, %v), want the loaded item", k, v, ok)
+		}
+	}
+
+	// all hits, no load
+	if _, err := c.GetManyReadThrough([]string{"a", "b"}); err != nil || len(src.calls) != 1 {
+		t.Errorf("cache.GetManyReadThrough() of the hits: error %v, LoadMany calls %d, want nil, 1", err, len(src.calls))
+	}
+
+	src.err = errors.New("fail")
+	got, err = c.GetManyReadThrough([]string{"a", "c"})
+	if err != src.err || !reflect.DeepEqual(got, map[string]string{"a": "1"}) {
+		t.Errorf("cache.GetManyReadThrough() = (%v, %v), want the hits with the error", got, err)
+	}
+}
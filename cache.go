@@ -1,31 +1,309 @@
 package expirecache
 
 import (
+	"container/heap"
+	"context"
+	"errors"
+	"math"
 	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type element[T any] struct {
+	// updated atomically under the read lock, keep first for 64-bit alignment
+	hits       uint64
+	lastAccess int64 // unix nanoseconds, 0 if never accessed
+
 	validUntil time.Time
+	hardUntil  time.Time // the expiration time before MarkStale, the cleaners keep the item until it
+	created    time.Time
 	data       T
 	size       uint64
+	sticky     bool
+	priority   Priority
+	savings    uint64
+	latency    time.Duration // saved by each hit, see WithSavedLatency
+	tags       []string
+	heapIdx    int    // index in the expiry heap
+	keyIdx     int    // index in the keys slice
+	seq        uint64 // insertion sequence number, kept on overwrite
+	epoch      uint64 // namespaces epoch when stored, see BumpNamespace
+	interned   *internedValue[T]
+	// the value can't be shrunk (see SetShrinkFunc), accessed under the write lock
+	unshrinkable bool
 }
 
-// Cache is an expiring cache.  It is safe for
+// expiryHeap is a min-heap of elements ordered by the expiration time
+type expiryHeap[T any] []*element[T]
+
+func (h expiryHeap[T]) Len() int { return len(h) }
+
+func (h expiryHeap[T]) Less(i, j int) bool { return h[i].validUntil.Before(h[j].validUntil) }
+
+func (h expiryHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIdx = i
+	h[j].heapIdx = j
+}
+
+func (h *expiryHeap[T]) Push(x any) {
+	e := x.(*element[T])
+	e.heapIdx = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// touch records an access to the element and returns its hits count, safe to call under the read lock
+func (e *element[T]) touch(now time.Time) uint64 {
+	hits := atomic.AddUint64(&e.hits, 1)
+	atomic.StoreInt64(&e.lastAccess, now.UnixNano())
+	return hits
+}
+
+// touch records a hit of the element, safe to call under the read lock.
+// It returns true if the element is due for the hot promotion, see SetHotPromotion.
+func (ec *Cache[K, T]) touch(e *element[T], now time.Time) (promote bool) {
+	hits := e.touch(now)
+	ec.saved(e)
+	return ec.promoteHits != 0 && hits == ec.promoteHits
+}
+
+// saved accumulates the savings of a hit of the element, safe to call with no lock held
+func (ec *Cache[K, T]) saved(e *element[T]) {
+	if e.savings != 0 {
+		atomic.AddUint64(&ec.stats.WorkSaved, e.savings)
+	}
+	if e.latency != 0 {
+		atomic.AddInt64((*int64)(&ec.stats.LatencySaved), int64(e.latency))
+	}
+}
+
+// SetOption configures an item stored with Set
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	sticky   bool
+	priority Priority
+	savings  uint64
+	latency  time.Duration
+	deps     any // []K, set by Cache.DependsOn
+	tags     []string
+}
+
+// WithSavings sets the cost saved by each hit of the item (e.g. the recompute or fetch cost on a miss),
+// accumulated in Stats.WorkSaved
+func WithSavings(savings uint64) SetOption {
+	return func(o *setOptions) {
+		o.savings = savings
+	}
+}
+
+// WithSavedLatency sets the latency saved by each hit of the item (e.g. the backend latency on a miss),
+// accumulated in Stats.LatencySaved
+func WithSavedLatency(d time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.latency = d
+	}
+}
+
+// Sticky marks the item to be preserved by Clear (but not by ClearAll)
+func Sticky() SetOption {
+	return func(o *setOptions) {
+		o.sticky = true
+	}
+}
+
+// Cache is an expiring cache.  It is safe for concurrent use.
+//
+// Expiration times are in seconds, int32 for Set and its variants (GetOrSet, TrySet, SetAuto, LoaderFunc),
+// int64 for the conditional and computing stores (SetIf, SetCtx, SwapWithOldTTL, ReplaceIfFits, ComputeIfAbsent,
+// Fetch, IncrementWindow) and EffectiveTTL, clamped to the int32 range.
+//
+// All user callbacks (OnSpill, loaders, Range functions) are invoked with no lock held,
+// so they may call back into the same cache without deadlocking, except for the ones deciding on a change
+// under the write lock, which must not call back into the cache: the SetIf and DeleteIf conditions,
+// the SetVetoEvict (also for the 2Q victims), SetShrinkFunc and SetValueInterning functions,
+// and the SetAdmission and SetUniqueKeysTracking hashes (invoked under the write lock by the stores).
 type Cache[K comparable, T any] struct {
+	// updated atomically, keep first for 64-bit alignment
+	stats Stats
+
 	sync.RWMutex
-	cache     map[K]element[T]
+	cache     Store[K, T]
+	cow       *cowStore[K, T] // the cache Store in the copy-on-write mode, see NewCopyOnWrite
 	keys      []K
+	seq       uint64 // last insertion sequence number
+	expiry    expiryHeap[T]
 	totalSize uint64
 	maxSize   uint64
+	tiers     map[string]time.Duration
+	sizeFunc  func(v T) uint64
+	shrink    ShrinkFunc[K, T]
+	vetoEvict func(k K, v T) bool
+	// items count limit and the eviction order, see New2Q
+	maxEntries int
+	twoQ       *twoQueue[K]
+	// interned values by the identity, see SetValueInterning
+	internID  func(v T) string
+	interned  map[string]*internedValue[T]
+	thrash    *thrashTracker[K]
+	admission *frequencySketch[K]
+	// in-flight ComputeIfAbsent calls
+	inflight      map[K]*call[T]
+	maxInflight   int
+	inflightBlock bool
+	// closed (and reset) when an in-flight call is done, for ComputeIfAbsent waiters
+	inflightFreed chan struct{}
+	// GetOrLoad loaders by the key prefix
+	loaders map[string]LoaderFunc[K, T]
+	// per-item snapshot formats, see SetSnapshotCodecs
+	snapshotFormat func(v T) string
+	snapshotCodecs map[string]SnapshotCodec[T]
+	// GetManyReadThrough source
+	batchSource BatchSource[K, T]
+	batchExpire int32
+	// keys refreshed in background by GetRevalidate
+	refreshing map[K]struct{}
+	readOnly   bool
+	// expired items are kept for serving by GetGrace, see SetGracePeriod
+	grace time.Duration
+	// the cleaners skip their runs, see PauseCleaner
+	cleanerPaused bool
+	// the interval of the last started cleaner, for Config
+	cleanerInterval time.Duration
+	// the cleaners are stopped (and the cache is closed in the closed mode), see Stop
+	stopped     bool
+	closed      bool
+	closeOnStop bool
+	// Set rate limit, see WithSetRateLimit
+	setLimit *tokenBucket
+	// last namespaces epoch and the epochs of the bumped namespaces, see BumpNamespace
+	epoch      uint64
+	namespaces atomic.Value // map[string]uint64
+	// TTLs extension while far from full, see SetTTLBoost
+	boostBelow  float64
+	boostFactor float64
+	boostMax    time.Duration
+	// hot promotion, see SetHotPromotion
+	promoteHits uint64
+	promoteTTL  time.Duration
+	// items count per priority band, indexed by Priority - PriorityLow
+	priorities [priorityBands]int
+	// SetCtx waits for room instead of evicting
+	blockWhenFull bool
+	// closed (and reset) when memory is freed, for SetCtx waiters
+	room chan struct{}
+
+	// dependency graph, see DependsOn
+	deps       map[K][]K
+	dependents map[K]map[K]struct{}
+	// keys per tag, see WithTags
+	tags map[string]map[K]struct{}
+	// items limits per tag, see SetTagLimit
+	tagLimits map[string]int
+	// distinct tags limit, see SetMaxTags
+	maxTags          int
+	dropLeastUsedTag bool
+
+	onSpill      func(k K, v T)
+	spillExpired bool
+
+	hitRate  atomic.Value // *hitRateTracker
+	misses   atomic.Value // *missTracker[K]
+	unique   atomic.Value // *uniqueKeys[K]
+	history  *statsHistory
+	latency  atomic.Value // *latencyRecorder
+	slowOps  atomic.Value // *slowOpWatcher
+	keyLocks keyLocks[K]
+
+	// removed items pending for onSpill, dispatched by unlock
+	spilled []spilledItem[K, T]
+}
+
+type spilledItem[K comparable, T any] struct {
+	k K
+	v T
+}
+
+// ErrUnknownTier is returned by SetTier for a tier that was not registered
+var ErrUnknownTier = errors.New("expirecache: unknown tier")
+
+// ErrReadOnly is returned by mutations with an error result when the cache is read-only
+var ErrReadOnly = errors.New("expirecache: cache is read-only")
+
+// ErrNotAdmitted is returned by Sets with an error result when the item is rejected by the admission filter, see SetAdmission
+var ErrNotAdmitted = errors.New("expirecache: item rejected by the admission filter")
+
+// Stats contains the cache counters
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64 // items evicted due to the maximum memory size
+	WorkSaved uint64 // sum of the hit items savings, see WithSavings
+	Reclaimed uint64 // sum of the sizes of the items removed from the cache (expired, evicted, deleted or cleared)
+
+	RateLimited  uint64        // Sets rejected due to the rate limit, see WithSetRateLimit
+	LatencySaved time.Duration // sum of the hit items saved latencies, see WithSavedLatency
 }
 
 // New creates a new cache with a maximum memory size
-func New[K comparable, T any](maxSize uint64) *Cache[K, T] {
-	return &Cache[K, T]{
-		cache:   make(map[K]element[T]),
-		maxSize: maxSize,
+func New[K comparable, T any](maxSize uint64, opts ...Option) *Cache[K, T] {
+	return NewWithStore[K, T](maxSize, make(mapStore[K, T]), opts...)
+}
+
+// NewWithStore creates a new cache with a maximum memory size, keeping the items in the empty store s.
+func NewWithStore[K comparable, T any](maxSize uint64, s Store[K, T], opts ...Option) *Cache[K, T] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ec := &Cache[K, T]{
+		cache:       s,
+		maxSize:     maxSize,
+		closeOnStop: o.closeOnStop,
+		maxEntries:  o.maxEntries,
+	}
+	if o.twoQueue {
+		ec.twoQ = newTwoQueue[K]()
+	}
+	if o.setRate > 0 {
+		ec.setLimit = newTokenBucket(o.setRate, o.setBurst, timeNow())
+	}
+	if o.preload != nil {
+		o.preload(ec)
+	}
+	return ec
+}
+
+// SetOnSpill sets a callback invoked for items evicted due to the maximum memory size,
+// e.g. for pushing them to a secondary store. If expired is true, it's also invoked for items removed by the cleaners.
+// The callback is invoked with no lock held.
+func (ec *Cache[K, T]) SetOnSpill(f func(k K, v T), expired bool) {
+	ec.Lock()
+	ec.onSpill = f
+	ec.spillExpired = expired
+	ec.Unlock()
+}
+
+// unlock releases the write lock and passes the removed items to onSpill
+func (ec *Cache[K, T]) unlock() {
+	spilled := ec.spilled
+	onSpill := ec.onSpill
+	ec.spilled = nil
+	ec.Unlock()
+	for _, item := range spilled {
+		onSpill(item.k, item.v)
 	}
 }
 
@@ -45,102 +323,1069 @@ func (ec *Cache[K, T]) Items() int {
 	return k
 }
 
+// Stats returns the current cache counters
+func (ec *Cache[K, T]) Stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadUint64(&ec.stats.Hits),
+		Misses:    atomic.LoadUint64(&ec.stats.Misses),
+		Evictions: atomic.LoadUint64(&ec.stats.Evictions),
+		WorkSaved: atomic.LoadUint64(&ec.stats.WorkSaved),
+		Reclaimed: atomic.LoadUint64(&ec.stats.Reclaimed),
+
+		RateLimited:  atomic.LoadUint64(&ec.stats.RateLimited),
+		LatencySaved: time.Duration(atomic.LoadInt64((*int64)(&ec.stats.LatencySaved))),
+	}
+}
+
+// StatsAndReset returns the current cache counters and resets them to zero.
+// Every counted event is returned exactly once by successive calls.
+func (ec *Cache[K, T]) StatsAndReset() Stats {
+	return Stats{
+		Hits:      atomic.SwapUint64(&ec.stats.Hits, 0),
+		Misses:    atomic.SwapUint64(&ec.stats.Misses, 0),
+		Evictions: atomic.SwapUint64(&ec.stats.Evictions, 0),
+		WorkSaved: atomic.SwapUint64(&ec.stats.WorkSaved, 0),
+		Reclaimed: atomic.SwapUint64(&ec.stats.Reclaimed, 0),
+
+		RateLimited:  atomic.SwapUint64(&ec.stats.RateLimited, 0),
+		LatencySaved: time.Duration(atomic.SwapInt64((*int64)(&ec.stats.LatencySaved), 0)),
+	}
+}
+
+// NextExpiry returns the soonest expiration time of the items in the cache, false if the cache is empty.
+// Expired items not yet removed by a cleaner are included, so it may be in the past.
+func (ec *Cache[K, T]) NextExpiry() (time.Time, bool) {
+	ec.RLock()
+	if len(ec.expiry) == 0 {
+		ec.RUnlock()
+		return time.Time{}, false
+	}
+	t := ec.expiry[0].validUntil
+	ec.RUnlock()
+	return t, true
+}
+
+// lookup counts a hit or a miss of the key
+func (ec *Cache[K, T]) lookup(k K, hit bool) {
+	if hit {
+		atomic.AddUint64(&ec.stats.Hits, 1)
+	} else {
+		atomic.AddUint64(&ec.stats.Misses, 1)
+	}
+	if hr, _ := ec.hitRate.Load().(*hitRateTracker); hr != nil {
+		hr.record(hit)
+	}
+	if mt, _ := ec.misses.Load().(*missTracker[K]); mt != nil {
+		mt.record(k, hit)
+	}
+	ec.seen(k)
+	if hit && ec.twoQ != nil {
+		ec.twoQ.access(k)
+	}
+}
+
 // Get returns the item from the cache
 func (ec *Cache[K, T]) Get(k K) (item T, ok bool) {
+	r := ec.latencyRecorder()
+	if r == nil {
+		return ec.getItem(k)
+	}
+	start := timeNow()
+	item, ok = ec.getItem(k)
+	r.Record(OpGet, timeNow().Sub(start))
+	return item, ok
+}
+
+func (ec *Cache[K, T]) getItem(k K) (item T, ok bool) {
+	if ec.cow != nil {
+		return ec.getLockFree(k)
+	}
+	now := timeNow()
 	ec.RLock()
-	v, ok := ec.cache[k]
-	ec.RUnlock()
-	if !ok || v.validUntil.Before(timeNow()) {
+	if ec.admission != nil {
+		ec.admission.increment(k)
+	}
+	v, ok := ec.get(k)
+	if !ok || !ec.alive(k, v, now) {
+		ec.RUnlock()
+		ec.lookup(k, false)
 		// Can't actually delete this element from the cache here since
 		// we can't remove the key from ec.keys without a linear search.
 		// It'll get removed during the next cleanup
-		var v element[T]
-		return v.data, false
+		return item, false
+	}
+	promote := ec.touch(v, now)
+	item = v.data
+	ec.RUnlock()
+	if promote {
+		ec.promote(k, v)
+	}
+	ec.lookup(k, true)
+	return item, true
+}
+
+// GetOrDefault returns the item from the cache, or def if it is missing or expired.
+func (ec *Cache[K, T]) GetOrDefault(k K, def T) T {
+	if item, ok := ec.Get(k); ok {
+		return item
+	}
+	return def
+}
+
+// EntryInfo contains an item and its metadata
+type EntryInfo[T any] struct {
+	Value      T
+	Size       uint64
+	Created    time.Time
+	LastAccess time.Time // zero if the item was never accessed
+	Hits       uint64
+	TTL        time.Duration // remaining time to live
+}
+
+// Inspect returns the item from the cache with its metadata. It doesn't count as an access.
+func (ec *Cache[K, T]) Inspect(k K) (info EntryInfo[T], ok bool) {
+	now := timeNow()
+	ec.RLock()
+	v, ok := ec.get(k)
+	if !ok || !ec.alive(k, v, now) {
+		ec.RUnlock()
+		return info, false
+	}
+	info = EntryInfo[T]{
+		Value:   v.data,
+		Size:    v.size,
+		Created: v.created,
+		Hits:    atomic.LoadUint64(&v.hits),
+		TTL:     v.validUntil.Sub(now),
+	}
+	if lastAccess := atomic.LoadInt64(&v.lastAccess); lastAccess != 0 {
+		info.LastAccess = time.Unix(0, lastAccess)
+	}
+	ec.RUnlock()
+	return info, true
+}
+
+// EntrySnapshot contains an item with its key and metadata
+type EntrySnapshot[K comparable, T any] struct {
+	Key     K
+	Value   T
+	Size    uint64
+	Expires time.Time
+	Created time.Time
+	Hits    uint64
+}
+
+// Entries returns a snapshot of all unexpired items with their metadata, taken under a single read lock.
+func (ec *Cache[K, T]) Entries() []EntrySnapshot[K, T] {
+	now := timeNow()
+	ec.RLock()
+	entries := make([]EntrySnapshot[K, T], 0, len(ec.keys))
+	for _, k := range ec.keys {
+		v := ec.elem(k)
+		if !ec.alive(k, v, now) {
+			continue
+		}
+		entries = append(entries, EntrySnapshot[K, T]{
+			Key:     k,
+			Value:   v.data,
+			Size:    v.size,
+			Expires: v.validUntil,
+			Created: v.created,
+			Hits:    atomic.LoadUint64(&v.hits),
+		})
 	}
-	return v.data, ok
+	ec.RUnlock()
+	return entries
 }
 
-// GetOrSet returns the item from the cache or sets a new variable if it doesn't exist
+// AgePercentiles returns the percentiles of the unexpired items ages (time since stored), with the nearest-rank method.
+// Each of ps is a fraction in [0, 1], e.g. 0.99 for p99. All percentiles are zero if the cache has no unexpired items.
+func (ec *Cache[K, T]) AgePercentiles(ps []float64) []time.Duration {
+	now := timeNow()
+	ec.RLock()
+	ages := make([]time.Duration, 0, len(ec.keys))
+	for _, k := range ec.keys {
+		v := ec.elem(k)
+		if ec.alive(k, v, now) {
+			ages = append(ages, now.Sub(v.created))
+		}
+	}
+	ec.RUnlock()
+
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	res := make([]time.Duration, len(ps))
+	if len(ages) == 0 {
+		return res
+	}
+	for i, p := range ps {
+		rank := int(math.Ceil(p*float64(len(ages)))) - 1
+		if rank < 0 {
+			rank = 0
+		} else if rank >= len(ages) {
+			rank = len(ages) - 1
+		}
+		res[i] = ages[rank]
+	}
+	return res
+}
+
+// neverExpires is the remaining time to live of the items counted as never expiring by TTLBands
+const neverExpires = 10 * 365 * 24 * time.Hour
+
+// TTLBands returns the counts of the unexpired items by the remaining time to live: soon (under a minute),
+// medium (1 to 5 minutes), later (over 5 minutes) and never (effectively never expiring, over 10 years,
+// e.g. stored with a math.MaxInt32 expire).
+func (ec *Cache[K, T]) TTLBands() (soon, medium, later, never int) {
+	now := timeNow()
+	ec.RLock()
+	for _, k := range ec.keys {
+		v := ec.elem(k)
+		if !ec.alive(k, v, now) {
+			continue
+		}
+		switch ttl := v.validUntil.Sub(now); {
+		case ttl < time.Minute:
+			soon++
+		case ttl <= 5*time.Minute:
+			medium++
+		case ttl <= neverExpires:
+			later++
+		default:
+			never++
+		}
+	}
+	ec.RUnlock()
+	return soon, medium, later, never
+}
+
+// GetOrSet returns the item from the cache or sets a new variable if it doesn't exist.
+// The lookup and the store are done under a single lock, so when concurrent callers race on an absent key,
+// the first to acquire the lock stores its value and all others get that stored value.
 func (ec *Cache[K, T]) GetOrSet(k K, newValue T, size uint64, expire int32) (item T) {
+	now := timeNow()
 	ec.Lock()
-	v, ok := ec.cache[k]
-	if !ok || v.validUntil.Before(timeNow()) {
-		ec.actualSet(k, newValue, size, expire)
-		ec.Unlock()
+	if ec.admission != nil {
+		ec.admission.increment(k)
+	}
+	v, ok := ec.get(k)
+	if !ok || !ec.alive(k, v, now) {
+		if ec.readOnly || !ec.allowSet() {
+			ec.Unlock()
+			ec.lookup(k, false)
+			return newValue
+		}
+		ec.actualSet(k, newValue, size, ec.ttl(expire))
+		ec.unlock()
+		ec.lookup(k, false)
 		return newValue
 	}
+	if ec.touch(v, now) {
+		ec.extend(k, v)
+	}
 	ec.Unlock()
+	ec.lookup(k, true)
 	return v.data
 }
 
 // Set adds an item to the cache, with an estimated size and expiration time in seconds.
-func (ec *Cache[K, T]) Set(k K, v T, size uint64, expire int32) {
+// Any expire value is representable (math.MaxInt32 is about 68 years), so a huge one never wraps
+// into an immediate expiration. The TTL may be extended while the cache is far from full, see SetTTLBoost.
+func (ec *Cache[K, T]) Set(k K, v T, size uint64, expire int32, opts ...SetOption) {
+	ec.TrySet(k, v, size, expire, opts...)
+}
+
+// TrySet adds an item to the cache like Set, it returns false if the item is rejected
+// because the cache is read-only, the Set rate limit is exceeded (see WithSetRateLimit)
+// or the admission filter doesn't admit it (see SetAdmission).
+func (ec *Cache[K, T]) TrySet(k K, v T, size uint64, expire int32, opts ...SetOption) bool {
+	if r := ec.latencyRecorder(); r != nil {
+		start := timeNow()
+		ok := ec.set(k, v, size, expire, opts...)
+		r.Record(OpSet, timeNow().Sub(start))
+		return ok
+	}
+	return ec.set(k, v, size, expire, opts...)
+}
+
+func (ec *Cache[K, T]) set(k K, v T, size uint64, expire int32, opts ...SetOption) bool {
+	ec.Lock()
+	held := ec.lockHeld()
+	if ec.readOnly || !ec.allowSet() {
+		ec.Unlock()
+		return false
+	}
+	stored := ec.actualSet(k, v, size, ec.ttl(expire), opts...)
+	hold := ec.holdTime(held)
+	ec.unlock()
+	ec.slowOp("set", hold)
+	return stored
+}
+
+// EffectiveTTL returns the TTL a Set of the key with the expiration time in seconds would apply now: the requested one,
+// clamped to the int32 range of Set like Fetch does, and extended by the TTL boost (see SetTTLBoost).
+// A negative TTL is stored as already expired. The cache has no per-key TTL policies, so the key doesn't change the result.
+func (ec *Cache[K, T]) EffectiveTTL(k K, expire int64) time.Duration {
+	ec.RLock()
+	ttl := ec.ttl(clampExpire(expire))
+	ec.RUnlock()
+	return ttl
+}
+
+// clampExpire clamps the expiration time in seconds to the int32 range of Set
+func clampExpire(expire int64) int32 {
+	if expire > math.MaxInt32 {
+		return math.MaxInt32
+	} else if expire < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(expire)
+}
+
+// SetBlockWhenFull switches SetCtx to wait for room when the cache is full, instead of evicting items.
+// Set always evicts.
+func (ec *Cache[K, T]) SetBlockWhenFull(block bool) {
 	ec.Lock()
-	ec.actualSet(k, v, size, expire)
+	ec.blockWhenFull = block
 	ec.Unlock()
 }
 
-func (ec *Cache[K, T]) actualSet(k K, v T, size uint64, expire int32) {
-	oldv, ok := ec.cache[k]
+// SetCtx adds an item to the cache like Set. If the cache is configured with SetBlockWhenFull
+// and the item doesn't fit, it waits for room until ctx is done and returns ctx.Err() on cancellation.
+// An item larger than the maximum memory size never fits. ErrReadOnly is returned if the cache is read-only.
+func (ec *Cache[K, T]) SetCtx(ctx context.Context, k K, v T, size uint64, expire int64, opts ...SetOption) error {
+	for {
+		ec.Lock()
+		if ec.readOnly {
+			err := ec.errReadOnly()
+			ec.Unlock()
+			return err
+		}
+		if !ec.blockWhenFull || ec.fits(k, size) {
+			if !ec.allowSet() {
+				ec.Unlock()
+				return ErrRateLimited
+			}
+			stored := ec.actualSet(k, v, size, ec.ttl(clampExpire(expire)), opts...)
+			ec.unlock()
+			if !stored {
+				return ErrNotAdmitted
+			}
+			return nil
+		}
+		if ec.room == nil {
+			ec.room = make(chan struct{})
+		}
+		room := ec.room
+		ec.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-room:
+		}
+	}
+}
+
+// fits checks if the item can be stored without evictions
+func (ec *Cache[K, T]) fits(k K, size uint64) bool {
+	if ec.maxSize == 0 {
+		return true
+	}
+	var oldSize uint64
+	if oldv, ok := ec.get(k); ok {
+		oldSize = oldv.size
+	}
+	rest := ec.totalSize - oldSize
+	return rest <= ec.maxSize && size <= ec.maxSize-rest
+}
+
+// freed wakes up SetCtx waiters
+func (ec *Cache[K, T]) freed() {
+	if ec.room != nil {
+		close(ec.room)
+		ec.room = nil
+	}
+}
+
+// Reserve evicts the oldest items (by the store time, skipping the vetoed ones) until bytes and entries items fit
+// within the maximum memory size and the maximum items count (see New2Q), so a batch of that size is then stored
+// without evictions. It returns the count of evicted items.
+func (ec *Cache[K, T]) Reserve(bytes uint64, entries int) int {
+	ec.Lock()
+	fits := func() bool {
+		if ec.maxEntries > 0 && len(ec.keys)+entries > ec.maxEntries {
+			return false
+		}
+		return ec.maxSize == 0 || (bytes <= ec.maxSize && ec.totalSize <= ec.maxSize-bytes)
+	}
+	if ec.readOnly || fits() {
+		ec.Unlock()
+		return 0
+	}
+	oldest := make([]*element[T], len(ec.keys))
+	for i, k := range ec.keys {
+		oldest[i] = ec.elem(k)
+	}
+	sort.Slice(oldest, func(i, j int) bool {
+		if oldest[i].created.Equal(oldest[j].created) {
+			return oldest[i].seq < oldest[j].seq
+		}
+		return oldest[i].created.Before(oldest[j].created)
+	})
+	var n int
+	for _, v := range oldest {
+		if fits() {
+			break
+		}
+		if ec.vetoed(v.keyIdx) {
+			continue
+		}
+		ec.evictAt(v.keyIdx)
+		n++
+	}
+	ec.unlock()
+	return n
+}
+
+// ReplaceIfFits replaces an existing unexpired item only if the new size keeps the cache within the maximum memory size,
+// so the update never causes evictions. It returns false if the item is absent or doesn't fit.
+func (ec *Cache[K, T]) ReplaceIfFits(k K, v T, size uint64, expire int64) bool {
+	now := timeNow()
+	ec.Lock()
+	oldv, ok := ec.get(k)
+	if ec.readOnly || !ok || !ec.alive(k, oldv, now) || !ec.fits(k, size) || !ec.allowSet() {
+		ec.Unlock()
+		return false
+	}
+	stored := ec.actualSet(k, v, size, ec.ttl(clampExpire(expire)))
+	ec.unlock()
+	return stored
+}
+
+// SwapWithOldTTL replaces the item and returns the old unexpired one with its remaining time to live
+// under a single lock, had is false if there was none. If the cache is read-only or the Set rate limit is exceeded
+// (see WithSetRateLimit), the item isn't stored.
+func (ec *Cache[K, T]) SwapWithOldTTL(k K, v T, size uint64, expire int64) (old T, oldTTL time.Duration, had bool) {
+	now := timeNow()
+	ec.Lock()
+	if oldv, ok := ec.get(k); ok && ec.alive(k, oldv, now) {
+		old, oldTTL, had = oldv.data, oldv.validUntil.Sub(now), true
+	}
+	if ec.readOnly || !ec.allowSet() {
+		ec.Unlock()
+		return old, oldTTL, had
+	}
+	ec.actualSet(k, v, size, ec.ttl(clampExpire(expire)))
+	ec.unlock()
+	return old, oldTTL, had
+}
+
+// RefreshIfBelow sets the time to live of an unexpired item to ttl, only if its remaining time to live is below threshold.
+// It returns whether the item was refreshed. Unlike sliding the expiration on every access,
+// the write lock is taken only for the refresh itself.
+func (ec *Cache[K, T]) RefreshIfBelow(k K, threshold, ttl time.Duration) bool {
+	now := timeNow()
+	ec.RLock()
+	v, ok := ec.get(k)
+	due := ok && ec.alive(k, v, now) && v.validUntil.Sub(now) < threshold
+	ec.RUnlock()
+	if !due {
+		return false
+	}
+
+	ec.Lock()
+	// recheck, it may be changed in the meantime
+	v, ok = ec.get(k)
+	if ec.readOnly || !ok || !ec.alive(k, v, now) || v.validUntil.Sub(now) >= threshold {
+		ec.Unlock()
+		return false
+	}
+	ec.expiresAt(k, v, now.Add(ttl))
+	ec.Unlock()
+	return true
+}
+
+// SetIf stores the item only if cond returns true for the current unexpired value (existed is false if there is none).
+// The check and the store are done under a single lock, so cond must not call back into the cache.
+// It returns whether the item was stored.
+func (ec *Cache[K, T]) SetIf(k K, v T, size uint64, expire int64, cond func(existing T, existed bool) bool) bool {
+	now := timeNow()
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return false
+	}
+	var existing T
+	oldv, ok := ec.get(k)
+	if ok && ec.alive(k, oldv, now) {
+		existing = oldv.data
+	} else {
+		ok = false
+	}
+	if !cond(existing, ok) || !ec.allowSet() {
+		ec.Unlock()
+		return false
+	}
+	stored := ec.actualSet(k, v, size, ec.ttl(clampExpire(expire)))
+	ec.unlock()
+	return stored
+}
+
+// RegisterTier registers (or replaces) a named TTL tier for use with SetTier.
+func (ec *Cache[K, T]) RegisterTier(name string, ttl time.Duration) {
+	ec.Lock()
+	if ec.tiers == nil {
+		ec.tiers = make(map[string]time.Duration)
+	}
+	ec.tiers[name] = ttl
+	ec.Unlock()
+}
+
+// SetTier adds an item to the cache, with an estimated size and expiration time taken from the named tier.
+// ErrUnknownTier is returned if the tier isn't registered.
+func (ec *Cache[K, T]) SetTier(k K, v T, size uint64, tier string) error {
+	ec.Lock()
+	if ec.readOnly {
+		err := ec.errReadOnly()
+		ec.Unlock()
+		return err
+	}
+	ttl, ok := ec.tiers[tier]
 	if !ok {
+		ec.Unlock()
+		return ErrUnknownTier
+	}
+	if !ec.allowSet() {
+		ec.Unlock()
+		return ErrRateLimited
+	}
+	stored := ec.actualSet(k, v, size, ttl)
+	ec.unlock()
+	if !stored {
+		return ErrNotAdmitted
+	}
+	return nil
+}
+
+// actualSet stores the item, it returns false if the item is rejected by the admission filter (see SetAdmission)
+func (ec *Cache[K, T]) actualSet(k K, v T, size uint64, ttl time.Duration, opts ...SetOption) bool {
+	victim, hasVictim, admitted := ec.admit(k, size)
+	if !admitted {
+		return false
+	}
+	ec.store(k, v, size, ttl, victim, hasVictim, opts...)
+	return true
+}
+
+// store stores the item admitted by admit, evicting the victim it was compared with first if needed
+func (ec *Cache[K, T]) store(k K, v T, size uint64, ttl time.Duration, victimKey K, hasVictim bool, opts ...SetOption) {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ec.seen(k)
+	ec.invalidateDependents(k)
+	ec.unlinkDeps(k)
+	if deps, ok := o.deps.([]K); ok {
+		ec.linkDeps(k, deps)
+	}
+
+	now := timeNow()
+	e := &element[T]{validUntil: now.Add(ttl), created: now, data: v, size: size, sticky: o.sticky, priority: o.priority, savings: o.savings, latency: o.latency, tags: o.tags, epoch: ec.epoch}
+	oldv, ok := ec.get(k)
+	victim := -1
+	if hasVictim {
+		// the dependents removal may have removed or moved the victim
+		if vv, found := ec.get(victimKey); found {
+			victim = vv.keyIdx
+		}
+	}
+	ec.priorities[e.priority-PriorityLow]++
+	if !ok {
+		if ec.thrash != nil {
+			ec.thrash.insert(k, now)
+		}
+		if ec.twoQ != nil {
+			ec.twoQ.insert(k)
+		}
+		ec.seq++
+		e.seq = ec.seq
+		e.keyIdx = len(ec.keys)
 		ec.keys = append(ec.keys, k)
+		heap.Push(&ec.expiry, e)
 	} else {
-		ec.totalSize -= oldv.size
+		if size < oldv.size {
+			ec.freed()
+		}
+		if ec.twoQ != nil {
+			ec.twoQ.access(k)
+		}
+		ec.totalSize -= ec.release(oldv)
+		ec.priorities[oldv.priority-PriorityLow]--
+		ec.unlinkTags(k, oldv.tags)
+		e.seq = oldv.seq
+		e.keyIdx = oldv.keyIdx
+		e.heapIdx = oldv.heapIdx
+		ec.expiry[e.heapIdx] = e
+		heap.Fix(&ec.expiry, e.heapIdx)
+	}
+
+	if size > math.MaxUint64-ec.totalSize {
+		// cap the size instead of wrapping the total size around, the item is accounted as too large to fit
+		e.size = math.MaxUint64 - ec.totalSize
 	}
+	ec.totalSize += ec.intern(e)
+	ec.cache.Set(k, (*Entry[T])(e))
+	ec.linkTags(k, e.tags)
 
-	ec.totalSize += size
-	ec.cache[k] = element[T]{validUntil: timeNow().Add(time.Duration(expire) * time.Second), data: v, size: size}
+	for (ec.maxSize > 0 && ec.totalSize > ec.maxSize) || ec.overEntries() {
+		if victim >= 0 {
+			ec.evictAt(victim)
+			victim = -1
+		} else if slot := ec.victim(); slot < 0 {
+			// all items are vetoed, the cache stays over the limits
+			break
+		} else if ec.overEntries() || (!ec.shrinkAt(slot) && !ec.shrinkAny()) {
+			ec.evictAt(slot)
+		}
+	}
+	ec.enforceTagLimits(e.tags)
+}
 
-	for ec.maxSize > 0 && ec.totalSize > ec.maxSize {
-		ec.randomEvict()
+// admit checks if a new item is admitted by the admission filter (see SetAdmission) when it needs evictions to fit.
+// It returns the first victim the item was compared with, evicted if the item is stored.
+func (ec *Cache[K, T]) admit(k K, size uint64) (victim K, hasVictim, admitted bool) {
+	if _, ok := ec.get(k); ok || ec.admission == nil || len(ec.keys) == 0 || ec.fits(k, size) {
+		return victim, false, true
+	}
+	slot := ec.victim()
+	if slot < 0 {
+		return victim, false, true
 	}
+	victim = ec.keys[slot]
+	return victim, true, ec.admission.admit(k, victim)
 }
 
-func (ec *Cache[K, T]) randomEvict() {
-	slot := rand.Intn(len(ec.keys))
+// LoaderFunc loads an item for the key, with an estimated size and expiration time in seconds.
+type LoaderFunc[K comparable, T any] func(k K) (v T, size uint64, expire int32, err error)
+
+// Range calls f sequentially for each unexpired item in the cache. If f returns false, Range stops the iteration.
+// Keys are snapshotted on start, so items added during the iteration may not be visited.
+// f is called with no lock held.
+func (ec *Cache[K, T]) Range(f func(k K, v T) bool) {
+	_ = ec.RangeLoad(nil, f)
+}
+
+// RangeLoad is like Range, but items expired by the time they are visited are refreshed
+// with load, stored in the cache and passed to f with the fresh value.
+// The iteration stops at the first load error, which is returned.
+//
+// load is called synchronously, so the iteration takes as long as loading all expired items (plus
+// a lock acquisition per key) and should be used with a Cleaner to avoid reloading long-abandoned items.
+func (ec *Cache[K, T]) RangeLoad(load LoaderFunc[K, T], f func(k K, v T) bool) error {
+	return ec.rangeKeys(ec.snapshotKeys(false), load, f)
+}
+
+// RangeOrdered is like Range, but items are visited in the insertion order (an overwrite keeps the item position).
+//
+// The keys slice doesn't keep the order, since removals move the last key into the freed slot to stay O(1).
+// Instead every item records an insertion sequence number and the keys snapshot is sorted by it,
+// so RangeOrdered costs an extra O(n log n) on each call, but nothing on writes.
+func (ec *Cache[K, T]) RangeOrdered(f func(k K, v T) bool) {
+	_ = ec.rangeKeys(ec.snapshotKeys(true), nil, f)
+}
+
+// snapshotKeys returns a copy of the keys slice, sorted by insertion if ordered is true
+func (ec *Cache[K, T]) snapshotKeys(ordered bool) []K {
+	ec.RLock()
+	keys := make([]K, len(ec.keys))
+	copy(keys, ec.keys)
+	if ordered {
+		seqs := make([]uint64, len(ec.keys))
+		for i, k := range keys {
+			seqs[i] = ec.elem(k).seq
+		}
+		sort.Sort(keysBySeq[K]{keys: keys, seqs: seqs})
+	}
+	ec.RUnlock()
+	return keys
+}
+
+type keysBySeq[K comparable] struct {
+	keys []K
+	seqs []uint64
+}
+
+func (s keysBySeq[K]) Len() int { return len(s.keys) }
+
+func (s keysBySeq[K]) Less(i, j int) bool { return s.seqs[i] < s.seqs[j] }
+
+func (s keysBySeq[K]) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.seqs[i], s.seqs[j] = s.seqs[j], s.seqs[i]
+}
+
+func (ec *Cache[K, T]) rangeKeys(keys []K, load LoaderFunc[K, T], f func(k K, v T) bool) error {
+	for _, k := range keys {
+		now := timeNow()
+		ec.RLock()
+		e, ok := ec.get(k)
+		var (
+			v       T
+			expired bool
+		)
+		if ok {
+			v = e.data
+			expired = !ec.alive(k, e, now)
+		}
+		ec.RUnlock()
+		if !ok || (expired && load == nil) {
+			continue
+		}
+		if expired {
+			var (
+				size   uint64
+				expire int32
+				err    error
+			)
+			if v, size, expire, err = load(k); err != nil {
+				return err
+			}
+			ec.Set(k, v, size, expire)
+		}
+		if !f(k, v) {
+			break
+		}
+	}
+	return nil
+}
+
+// Delete removes the item (and the items depending on it) from the cache,
+// returns false if the key isn't in the cache (or the cache is read-only).
+func (ec *Cache[K, T]) Delete(k K) bool {
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return false
+	}
+	ec.invalidateDependents(k)
+	v, ok := ec.get(k)
+	if ok {
+		ec.removeAt(v.keyIdx)
+	}
+	ec.Unlock()
+	return ok
+}
+
+// DeleteIf removes the unexpired item (and the items depending on it) only if cond returns true for its value.
+// The check and the removal are done under a single lock, so cond must not call back into the cache.
+// It returns whether the item was removed.
+func (ec *Cache[K, T]) DeleteIf(k K, cond func(v T) bool) bool {
+	now := timeNow()
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return false
+	}
+	v, ok := ec.get(k)
+	if !ok || !ec.alive(k, v, now) || !cond(v.data) {
+		ec.Unlock()
+		return false
+	}
+	ec.invalidateDependents(k)
+	ec.removeAt(v.keyIdx)
+	ec.Unlock()
+	return true
+}
+
+// ExpireOlderThan removes all items stored before t (e.g. the deploy time) and returns their count.
+// Like Delete, the items depending on the removed ones are invalidated too (see DependsOn), they aren't counted.
+func (ec *Cache[K, T]) ExpireOlderThan(t time.Time) int {
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return 0
+	}
+	var old []K
+	for _, k := range ec.keys {
+		if ec.elem(k).created.Before(t) {
+			old = append(old, k)
+		}
+	}
+	var n int
+	for _, k := range old {
+		// may be already removed as a dependent
+		if v, ok := ec.get(k); ok {
+			ec.invalidateDependents(k)
+			ec.removeAt(v.keyIdx)
+			n++
+		}
+	}
+	ec.Unlock()
+	return n
+}
+
+// TrimIdleExpired removes the expired items not accessed for idle (since the creation if never accessed)
+// and returns their count. Expired items accessed more recently are kept, even if past the grace period,
+// until the cleaners remove them.
+func (ec *Cache[K, T]) TrimIdleExpired(idle time.Duration) int {
+	now := timeNow()
+	idleSince := now.Add(-idle).UnixNano()
+
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return 0
+	}
+	var n int
+	for i := 0; i < len(ec.keys); i++ {
+		k := ec.keys[i]
+		v := ec.elem(k)
+		if !v.validUntil.Before(now) {
+			continue
+		}
+		accessed := atomic.LoadInt64(&v.lastAccess)
+		if accessed == 0 {
+			accessed = v.created.UnixNano()
+		}
+		if accessed <= idleSince {
+			ec.spillExpiredItem(k, v)
+			ec.removeAt(i)
+			i-- // so we reprocess this index
+			n++
+		}
+	}
+	ec.unlock()
+	return n
+}
+
+// SetReadOnly switches the read-only mode. While the cache is read-only, all mutations are rejected:
+// Set, Clear, ClearAll are no-op, GetOrSet returns the new value without storing it, Delete and ReplaceIfFits
+// return false, SetTier returns ErrReadOnly. Cleaners are paused too, so nothing is removed from the cache.
+// Get and the other reads continue serving unexpired items. A closed cache stays read-only (see CloseOnStop).
+func (ec *Cache[K, T]) SetReadOnly(ro bool) {
+	ec.Lock()
+	ec.readOnly = ro || ec.closed
+	ec.Unlock()
+}
+
+// Clear removes all items from the cache, except sticky ones.
+func (ec *Cache[K, T]) Clear() {
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return
+	}
+	for i := 0; i < len(ec.keys); i++ {
+		if !ec.elem(ec.keys[i]).sticky {
+			ec.removeAt(i)
+			i-- // so we reprocess this index
+		}
+	}
+	ec.Unlock()
+}
+
+// ClearAll removes all items from the cache, including sticky ones.
+func (ec *Cache[K, T]) ClearAll() {
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return
+	}
+	ec.clearAll()
+	ec.Unlock()
+}
+
+// Rotate removes all items from the cache (including sticky ones) like ClearAll and returns the unexpired ones,
+// in a single lock hold, e.g. for processing the items accumulated over a window: each stored item is returned
+// by exactly one Rotate. It returns nil if the cache is read-only.
+func (ec *Cache[K, T]) Rotate() map[K]T {
+	now := timeNow()
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return nil
+	}
+	items := make(map[K]T, len(ec.keys))
+	for _, k := range ec.keys {
+		if v := ec.elem(k); ec.alive(k, v, now) {
+			items[k] = v.data
+		}
+	}
+	ec.clearAll()
+	ec.Unlock()
+	return items
+}
+
+// clearAll removes all items from the cache, must be called under the lock
+func (ec *Cache[K, T]) clearAll() {
+	atomic.AddUint64(&ec.stats.Reclaimed, ec.totalSize)
+	if ec.cow != nil {
+		ec.cow.clear()
+	} else {
+		for _, k := range ec.keys {
+			ec.cache.Delete(k)
+		}
+	}
+	ec.keys = nil
+	ec.expiry = nil
+	ec.deps = nil
+	ec.dependents = nil
+	ec.tags = nil
+	ec.interned = nil
+	ec.totalSize = 0
+	ec.priorities = [priorityBands]int{}
+	if ec.twoQ != nil {
+		ec.twoQ.reset()
+	}
+	ec.freed()
+}
+
+// removeAt removes the item for the key at idx in ec.keys, the last key is moved to idx
+func (ec *Cache[K, T]) removeAt(idx int) *element[T] {
+	k := ec.keys[idx]
+	v := ec.elem(k)
+
+	last := len(ec.keys) - 1
+	if idx != last {
+		ec.keys[idx] = ec.keys[last]
+		ec.elem(ec.keys[idx]).keyIdx = idx
+	}
+	ec.keys = ec.keys[:last]
+
+	released := ec.release(v)
+	ec.totalSize -= released
+	atomic.AddUint64(&ec.stats.Reclaimed, released)
+	ec.priorities[v.priority-PriorityLow]--
+	heap.Remove(&ec.expiry, v.heapIdx)
+	ec.cache.Delete(k)
+	ec.unlinkDeps(k)
+	ec.unlinkTags(k, v.tags)
+	if ec.twoQ != nil {
+		ec.twoQ.remove(k)
+	}
+	ec.freed()
+
+	return v
+}
+
+// victim returns the index in ec.keys of the item to evict due to the maximum memory size.
+// A random item is chosen, unless the 2Q policy (see New2Q) or priorities (see WithPriority) are used.
+// The vetoed items are skipped (see SetVetoEvict), -1 is returned if all items are vetoed.
+func (ec *Cache[K, T]) victim() int {
+	if ec.twoQ != nil {
+		return ec.twoQueueVictim()
+	}
+	if ec.mixedPriorities() {
+		return ec.priorityVictim()
+	}
+	if ec.vetoEvict == nil {
+		return rand.Intn(len(ec.keys))
+	}
+	// the first item not vetoed from a random start
+	start := rand.Intn(len(ec.keys))
+	for i := range ec.keys {
+		if slot := (start + i) % len(ec.keys); !ec.vetoed(slot) {
+			return slot
+		}
+	}
+	return -1
+}
+
+// evictAt removes the item for the key at slot in ec.keys due to the maximum memory size
+func (ec *Cache[K, T]) evictAt(slot int) {
 	k := ec.keys[slot]
+	if ec.twoQ != nil {
+		ec.twoQ.evicted(k, ec.twoQueueCapacity()/2)
+	}
+	v := ec.removeAt(slot)
+	if ec.thrash != nil {
+		ec.thrash.evict(k, timeNow())
+	}
+
+	atomic.AddUint64(&ec.stats.Evictions, 1)
+	if ec.onSpill != nil {
+		ec.spilled = append(ec.spilled, spilledItem[K, T]{k: k, v: v.data})
+	}
+}
 
-	ec.keys[slot] = ec.keys[len(ec.keys)-1]
-	ec.keys = ec.keys[:len(ec.keys)-1]
+func (ec *Cache[K, T]) spillExpiredItem(k K, v *element[T]) {
+	if ec.onSpill != nil && ec.spillExpired {
+		ec.spilled = append(ec.spilled, spilledItem[K, T]{k: k, v: v.data})
+	}
+}
 
-	v := ec.cache[k]
-	ec.totalSize -= v.size
+// PauseCleaner pauses the cleaners (e.g. during a bulk load): their goroutines keep waking up, but skip the runs
+// until ResumeCleaner. Expired items are still missed by Get.
+func (ec *Cache[K, T]) PauseCleaner() {
+	ec.Lock()
+	ec.cleanerPaused = true
+	ec.Unlock()
+}
 
-	delete(ec.cache, k)
+// ResumeCleaner resumes the cleaners paused by PauseCleaner, the next runs remove all items expired in the meantime.
+func (ec *Cache[K, T]) ResumeCleaner() {
+	ec.Lock()
+	ec.cleanerPaused = false
+	ec.Unlock()
 }
 
 // Cleaner starts a goroutine which wakes up periodically and removes all expired items from the cache.
 func (ec *Cache[K, T]) Cleaner(d time.Duration) {
+	ec.startCleaner(d)
 
 	for {
 		cleanerSleep(d)
+		if ec.Stopped() {
+			return
+		}
 
-		now := timeNow()
-		ec.Lock()
+		ec.cleanAll(timeNow())
 
-		// We could potentially be holding this lock for a long time,
-		// but since we keep the cache expiration times small, we
-		// expect only a small number of elements here to loop over
+		cleanerDone()
+	}
+}
 
-		for i := 0; i < len(ec.keys); i++ {
+// cleanAll removes all items expired at now.
+func (ec *Cache[K, T]) cleanAll(now time.Time) {
+	// We could potentially be holding this lock for a long time after a pause
+	// (long GC, VM suspend) with a massive backlog of expired elements,
+	// so check keys in bounded batches and release the lock in between.
+	// Keys moved or appended by other writers between batches are checked
+	// at the end or during the next cleanup.
+	i := 0
+	for {
+		var checked int
+		ec.Lock()
+		held := ec.lockHeld()
+		if ec.readOnly || ec.cleanerPaused {
+			ec.Unlock()
+			return
+		}
+		for ; i < len(ec.keys) && checked < cleanerBatchSize; checked++ {
 			k := ec.keys[i]
-			v := ec.cache[k]
-			if v.validUntil.Before(now) {
-				ec.totalSize -= v.size
-				delete(ec.cache, k)
-
-				ec.keys[i] = ec.keys[len(ec.keys)-1]
-				ec.keys = ec.keys[:len(ec.keys)-1]
-				i-- // so we reprocess this index
+			v := ec.elem(k)
+			if ec.pastGrace(v, now) || ec.stale(k, v) {
+				ec.spillExpiredItem(k, v)
+				ec.removeAt(i)
+				// don't advance, so we reprocess this index
+			} else {
+				i++
 			}
 		}
-
-		ec.Unlock()
-		cleanerDone()
+		done := i >= len(ec.keys)
+		hold := ec.holdTime(held)
+		ec.unlock()
+		ec.slowOp("cleaner", hold)
+		cleanerBatch(checked)
+		if done {
+			return
+		}
 	}
 }
 
 func (ec *Cache[K, T]) StoppableApproximateCleaner(d time.Duration, exit <-chan struct{}) {
+	ec.startCleaner(d)
 	for {
 		select {
 		case <-exit:
@@ -149,6 +1394,9 @@ func (ec *Cache[K, T]) StoppableApproximateCleaner(d time.Duration, exit <-chan
 		}
 
 		cleanerSleep(d)
+		if ec.Stopped() {
+			return
+		}
 
 		ec.clean(timeNow())
 
@@ -159,8 +1407,12 @@ func (ec *Cache[K, T]) StoppableApproximateCleaner(d time.Duration, exit <-chan
 
 // ApproximateCleaner starts a goroutine which wakes up periodically and removes a sample of expired items from the cache.
 func (ec *Cache[K, T]) ApproximateCleaner(d time.Duration) {
+	ec.startCleaner(d)
 	for {
 		cleanerSleep(d)
+		if ec.Stopped() {
+			return
+		}
 
 		ec.clean(timeNow())
 
@@ -178,20 +1430,24 @@ func (ec *Cache[K, T]) clean(now time.Time) {
 		var cleaned int
 		// by doing short iterations and releasing the lock in between, we don't block other requests from progressing.
 		ec.Lock()
+		held := ec.lockHeld()
+		if ec.readOnly || ec.cleanerPaused {
+			ec.Unlock()
+			return
+		}
 		for i := 0; len(ec.keys) > 0 && i < sampleSize; i++ {
 			idx := rand.Intn(len(ec.keys))
 			k := ec.keys[idx]
-			v := ec.cache[k]
-			if v.validUntil.Before(now) {
-				ec.totalSize -= v.size
-				delete(ec.cache, k)
-
-				ec.keys[idx] = ec.keys[len(ec.keys)-1]
-				ec.keys = ec.keys[:len(ec.keys)-1]
+			v := ec.elem(k)
+			if ec.pastGrace(v, now) || ec.stale(k, v) {
+				ec.spillExpiredItem(k, v)
+				ec.removeAt(idx)
 				cleaned++
 			}
 		}
-		ec.Unlock()
+		hold := ec.holdTime(held)
+		ec.unlock()
+		ec.slowOp("approximate cleaner", hold)
 		if cleaned < rerunCount {
 			// "clean enough"
 			return
@@ -203,4 +1459,10 @@ var (
 	timeNow      = time.Now
 	cleanerSleep = time.Sleep
 	cleanerDone  = func() {}
+	// cleanerBatch is called after each Cleaner batch with the count of checked keys
+	cleanerBatch = func(int) {}
+	// cleanerBatchSize is the maximum number of keys checked by the Cleaner under a single lock hold
+	cleanerBatchSize = 1024
+	statsWindowSleep = time.Sleep
+	statsWindowDone  = func() {}
 )
@@ -1,7 +1,14 @@
 package expirecache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -12,16 +19,36 @@ func TestNew(t *testing.T) {
 	_ = New[string, string](1024)
 }
 
+// mockCleanerSleep returns a cleaner sleep hook waiting for a value from sleep: true to wake up,
+// false to stop the cleaner goroutine, so it doesn't outlive the test and run with the hooks of another one
+func mockCleanerSleep(sleep <-chan bool) func(time.Duration) {
+	return func(_ time.Duration) {
+		if !<-sleep {
+			runtime.Goexit()
+		}
+	}
+}
+
+// stopCleaner stops the cleaner goroutine waiting in the mockCleanerSleep hook, before the hooks are restored
+func stopCleaner(sleep chan<- bool) {
+	select {
+	case sleep <- false:
+	case <-time.After(time.Second):
+		// stuck elsewhere after a failure
+	}
+}
+
 func TestCacheExpire(t *testing.T) {
 
-	c := &Cache[string, string]{cache: make(map[string]element[string])}
+	c := &Cache[string, string]{cache: make(mapStore[string, string])}
 
 	sleep := make(chan bool)
-	cleanerSleep = func(_ time.Duration) { <-sleep }
+	cleanerSleep = mockCleanerSleep(sleep)
 	done := make(chan bool)
 	cleanerDone = func() { <-done }
 
 	defer func() {
+		stopCleaner(sleep)
 		cleanerSleep = time.Sleep
 		cleanerDone = func() {}
 		timeNow = time.Now
@@ -134,6 +161,1440 @@ func TestCacheExpire(t *testing.T) {
 
 }
 
+func TestCacheTier(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.RegisterTier("short", 30*time.Second)
+	c.RegisterTier("medium", 5*time.Minute)
+	c.RegisterTier("long", time.Hour)
+
+	if err := c.SetTier("foo", "bar", 3, "short"); err != nil {
+		t.Fatalf("SetTier(short) error: %v", err)
+	}
+	if err := c.SetTier("baz", "qux", 3, "medium"); err != nil {
+		t.Fatalf("SetTier(medium) error: %v", err)
+	}
+	if err := c.SetTier("zot", "bork", 4, "long"); err != nil {
+		t.Fatalf("SetTier(long) error: %v", err)
+	}
+	if err := c.SetTier("none", "none", 4, "unknown"); err != ErrUnknownTier {
+		t.Errorf("SetTier(unknown) error = %v, want %v", err, ErrUnknownTier)
+	}
+	if _, ok := c.Get("none"); ok {
+		t.Errorf("SetTier(unknown) should not store the item")
+	}
+
+	tests := []struct {
+		offset time.Duration
+		foo    bool
+		baz    bool
+		zot    bool
+	}{
+		{0, true, true, true},
+		{time.Minute, false, true, true},
+		{10 * time.Minute, false, false, true},
+		{2 * time.Hour, false, false, false},
+	}
+	for _, tt := range tests {
+		timeNow = func() time.Time { return t0.Add(tt.offset) }
+		if _, ok := c.Get("foo"); ok != tt.foo {
+			t.Errorf("at +%v cache.Get(foo) = %v, want %v", tt.offset, ok, tt.foo)
+		}
+		if _, ok := c.Get("baz"); ok != tt.baz {
+			t.Errorf("at +%v cache.Get(baz) = %v, want %v", tt.offset, ok, tt.baz)
+		}
+		if _, ok := c.Get("zot"); ok != tt.zot {
+			t.Errorf("at +%v cache.Get(zot) = %v, want %v", tt.offset, ok, tt.zot)
+		}
+	}
+}
+
+func TestCacheCleanerBacklog(t *testing.T) {
+	c := New[int, int](0)
+
+	sleep := make(chan bool)
+	cleanerSleep = mockCleanerSleep(sleep)
+	done := make(chan bool)
+	cleanerDone = func() { <-done }
+	var batches, maxBatch int
+	cleanerBatch = func(n int) {
+		batches++
+		if n > maxBatch {
+			maxBatch = n
+		}
+	}
+	cleanerBatchSize = 100
+
+	defer func() {
+		stopCleaner(sleep)
+		cleanerSleep = time.Sleep
+		cleanerDone = func() {}
+		cleanerBatch = func(int) {}
+		cleanerBatchSize = 1024
+		timeNow = time.Now
+	}()
+
+	go c.Cleaner(5 * time.Minute)
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	const expired = 10000
+	for i := 0; i < expired; i++ {
+		c.Set(i, i, 1, 30)
+	}
+	for i := expired; i < expired+10; i++ {
+		c.Set(i, i, 1, 3600)
+	}
+
+	// simulate a long pause
+	timeNow = func() time.Time { return t0.Add(10 * time.Minute) }
+	sleep <- true
+	done <- true
+
+	if maxBatch > cleanerBatchSize {
+		t.Errorf("cleaner checked %d keys under a single lock, want <= %d", maxBatch, cleanerBatchSize)
+	}
+	if batches < expired/cleanerBatchSize {
+		t.Errorf("cleaner batches = %d, want >= %d", batches, expired/cleanerBatchSize)
+	}
+	if c.Items() != 10 {
+		t.Errorf("items after cleanup = %d, want %d", c.Items(), 10)
+	}
+	if c.Size() != 10 {
+		t.Errorf("size after cleanup = %d, want %d", c.Size(), 10)
+	}
+	for i := expired; i < expired+10; i++ {
+		if _, ok := c.Get(i); !ok {
+			t.Errorf("cache.Get(%d) should be present", i)
+		}
+	}
+}
+
+func TestCacheClearSticky(t *testing.T) {
+	c := New[string, string](0)
+
+	c.Set("foo", "bar", 3, 60)
+	c.Set("config", "bootstrap", 9, 3600, Sticky())
+	c.Set("baz", "qux", 3, 60)
+
+	c.Clear()
+
+	if _, ok := c.Get("foo"); ok {
+		t.Errorf("cache.Get(foo) should be cleared")
+	}
+	if _, ok := c.Get("baz"); ok {
+		t.Errorf("cache.Get(baz) should be cleared")
+	}
+	if v, ok := c.Get("config"); !ok || v != "bootstrap" {
+		t.Errorf("cache.Get(config) = (%v, %v), want (bootstrap, true)", v, ok)
+	}
+	if c.Items() != 1 {
+		t.Errorf("items after Clear = %d, want %d", c.Items(), 1)
+	}
+	if c.Size() != 9 {
+		t.Errorf("size after Clear = %d, want %d", c.Size(), 9)
+	}
+
+	c.ClearAll()
+
+	if _, ok := c.Get("config"); ok {
+		t.Errorf("cache.Get(config) should be cleared by ClearAll")
+	}
+	if c.Items() != 0 || c.Size() != 0 {
+		t.Errorf("items, size after ClearAll = %d, %d, want 0, 0", c.Items(), c.Size())
+	}
+}
+
+func TestCacheInspect(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, 60)
+
+	info, ok := c.Inspect("foo")
+	if !ok {
+		t.Fatalf("cache.Inspect(foo) should be present")
+	}
+	want := EntryInfo[string]{Value: "bar", Size: 3, Created: t0, TTL: time.Minute}
+	if info != want {
+		t.Errorf("cache.Inspect(foo) before access = %+v, want %+v", info, want)
+	}
+
+	t1 := t0.Add(10 * time.Second)
+	timeNow = func() time.Time { return t1 }
+	c.Get("foo")
+	c.Get("foo")
+	t2 := t0.Add(20 * time.Second)
+	timeNow = func() time.Time { return t2 }
+	c.Get("foo")
+
+	info, ok = c.Inspect("foo")
+	if !ok {
+		t.Fatalf("cache.Inspect(foo) should be present")
+	}
+	want = EntryInfo[string]{Value: "bar", Size: 3, Created: t0, LastAccess: time.Unix(0, t2.UnixNano()), Hits: 3, TTL: 40 * time.Second}
+	if info != want {
+		t.Errorf("cache.Inspect(foo) after access = %+v, want %+v", info, want)
+	}
+
+	if _, ok = c.Inspect("baz"); ok {
+		t.Errorf("cache.Inspect(baz) should be absent")
+	}
+
+	timeNow = func() time.Time { return t0.Add(2 * time.Minute) }
+	if _, ok = c.Inspect("foo"); ok {
+		t.Errorf("cache.Inspect(foo) should be expired")
+	}
+}
+
+func TestCacheStatsAndReset(t *testing.T) {
+	c := New[int, int](10)
+
+	const (
+		workers = 8
+		ops     = 10000
+	)
+	for i := 0; i < workers; i++ {
+		c.Set(i, i, 1, 3600)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	var sum Stats
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			st := c.StatsAndReset()
+			sum.Hits += st.Hits
+			sum.Misses += st.Misses
+			sum.Evictions += st.Evictions
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < ops; n++ {
+				c.Get(i)      // hit
+				c.Get(-i - 1) // miss
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	<-collected
+
+	// overflow the cache to evict
+	for i := workers; i < workers+5; i++ {
+		c.Set(i, i, 1, 3600)
+	}
+
+	st := c.StatsAndReset()
+	sum.Hits += st.Hits
+	sum.Misses += st.Misses
+	sum.Evictions += st.Evictions
+
+	want := Stats{Hits: workers * ops, Misses: workers * ops, Evictions: 3}
+	if sum != want {
+		t.Errorf("summed StatsAndReset = %+v, want %+v", sum, want)
+	}
+	if st = c.Stats(); st != (Stats{}) {
+		t.Errorf("Stats after reset = %+v, want zero", st)
+	}
+}
+
+func TestCacheRangeLoad(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, 30)
+	c.Set("baz", "qux", 3, 60)
+	c.Set("zot", "bork", 4, 120)
+
+	timeNow = func() time.Time { return t0.Add(90 * time.Second) }
+
+	got := make(map[string]string)
+	c.Range(func(k, v string) bool {
+		got[k] = v
+		return true
+	})
+	if want := map[string]string{"zot": "bork"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.Range() = %v, want %v", got, want)
+	}
+
+	var loaded []string
+	load := func(k string) (string, uint64, int32, error) {
+		loaded = append(loaded, k)
+		return "fresh " + k, 6 + uint64(len(k)), 60, nil
+	}
+	got = make(map[string]string)
+	if err := c.RangeLoad(load, func(k, v string) bool {
+		got[k] = v
+		return true
+	}); err != nil {
+		t.Fatalf("cache.RangeLoad() error = %v", err)
+	}
+	if want := map[string]string{"foo": "fresh foo", "baz": "fresh baz", "zot": "bork"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.RangeLoad() = %v, want %v", got, want)
+	}
+	sort.Strings(loaded)
+	if want := []string{"baz", "foo"}; !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded keys = %v, want %v", loaded, want)
+	}
+	if v, ok := c.Get("foo"); !ok || v != "fresh foo" {
+		t.Errorf("cache.Get(foo) = (%v, %v), want (fresh foo, true)", v, ok)
+	}
+	if c.Size() != 9+9+4 {
+		t.Errorf("size after RangeLoad = %d, want %d", c.Size(), 9+9+4)
+	}
+
+	timeNow = func() time.Time { return t0.Add(time.Hour) }
+	errLoad := errors.New("load failed")
+	if err := c.RangeLoad(func(string) (string, uint64, int32, error) {
+		return "", 0, 0, errLoad
+	}, func(k, v string) bool {
+		t.Errorf("cache.RangeLoad() unexpected item %s", k)
+		return true
+	}); err != errLoad {
+		t.Errorf("cache.RangeLoad() error = %v, want %v", err, errLoad)
+	}
+}
+
+func TestCacheOnSpill(t *testing.T) {
+	c := New[string, string](10)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	spilled := make(map[string]string)
+	c.SetOnSpill(func(k, v string) {
+		spilled[k] = v
+	}, false)
+
+	c.Set("foo", "bar", 4, 30)
+	c.Set("baz", "qux", 4, 60)
+	c.Set("zot", "bork", 4, 120)
+
+	if len(spilled) != 1 {
+		t.Fatalf("spilled = %v, want 1 item", spilled)
+	}
+	for k, v := range spilled {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("spilled key %s should be evicted", k)
+		}
+		if want := map[string]string{"foo": "bar", "baz": "qux", "zot": "bork"}[k]; v != want {
+			t.Errorf("spilled %s = %s, want %s", k, v, want)
+		}
+		delete(spilled, k)
+	}
+
+	// expired items are not spilled by default
+	timeNow = func() time.Time { return t0.Add(time.Hour) }
+	c.clean(timeNow())
+	if len(spilled) != 0 {
+		t.Errorf("expired items should not be spilled, got %v", spilled)
+	}
+
+	c.SetOnSpill(func(k, v string) {
+		spilled[k] = v
+	}, true)
+	timeNow = func() time.Time { return t0 }
+	c.Set("foo", "bar", 4, 30)
+	timeNow = func() time.Time { return t0.Add(time.Hour) }
+	c.cleanAll(timeNow())
+	if want := map[string]string{"foo": "bar"}; !reflect.DeepEqual(spilled, want) {
+		t.Errorf("spilled expired = %v, want %v", spilled, want)
+	}
+}
+
+func TestCacheCallbackReentrant(t *testing.T) {
+	c := New[string, string](10)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var spilled int
+		c.SetOnSpill(func(k, v string) {
+			spilled++
+			// call back into the cache from the callback
+			c.Get(k)
+			c.Set("spilled", k, 1, 60)
+		}, true)
+
+		c.Set("foo", "bar", 4, 30)
+		c.Set("baz", "qux", 4, 60)
+		c.Set("zot", "bork", 4, 120)
+		if spilled == 0 {
+			t.Errorf("OnSpill should be called")
+		}
+
+		timeNow = func() time.Time { return t0.Add(90 * time.Second) }
+		c.RangeLoad(func(k string) (string, uint64, int32, error) {
+			c.Get(k)
+			c.Set(k+"-loaded", "loaded", 1, 60)
+			return "fresh", 1, 60, nil
+		}, func(k, v string) bool {
+			c.Get(k)
+			return true
+		})
+		c.Range(func(k, v string) bool {
+			c.Set(k, v, 1, 60)
+			return true
+		})
+		c.cleanAll(t0.Add(time.Hour))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reentrant callback deadlocked")
+	}
+}
+
+func TestCacheNextExpiry(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	if _, ok := c.NextExpiry(); ok {
+		t.Errorf("NextExpiry() on empty cache should be false")
+	}
+
+	checkNext := func(want time.Duration) {
+		t.Helper()
+		next, ok := c.NextExpiry()
+		if !ok {
+			t.Fatalf("NextExpiry() should be true")
+		}
+		if !next.Equal(t0.Add(want)) {
+			t.Errorf("NextExpiry() = +%v, want +%v", next.Sub(t0), want)
+		}
+	}
+
+	c.Set("zot", "bork", 4, 120)
+	checkNext(120 * time.Second)
+	c.Set("baz", "qux", 3, 60)
+	checkNext(60 * time.Second)
+	c.Set("foo", "bar", 3, 90)
+	checkNext(60 * time.Second)
+	c.Set("bork", "bork", 4, 30)
+	checkNext(30 * time.Second)
+
+	// overwrite with a longer TTL
+	c.Set("bork", "bork", 4, 300)
+	checkNext(60 * time.Second)
+
+	// remove expired
+	c.cleanAll(t0.Add(75 * time.Second))
+	checkNext(90 * time.Second)
+	c.cleanAll(t0.Add(100 * time.Second))
+	checkNext(120 * time.Second)
+
+	c.Clear()
+	if _, ok := c.NextExpiry(); ok {
+		t.Errorf("NextExpiry() on cleared cache should be false")
+	}
+}
+
+func TestCacheReplaceIfFits(t *testing.T) {
+	c := New[string, string](10)
+
+	c.Set("foo", "bar", 3, 60)
+	c.Set("baz", "qux", 4, 60)
+
+	tests := []struct {
+		key  string
+		size uint64
+		want bool
+	}{
+		{"foo", 6, true},  // 6+4 == 10
+		{"foo", 7, false}, // 7+4 > 10
+		{"baz", 4, true},  // same size
+		{"baz", 5, false}, // 6+5 > 10
+		{"foo", 1, true},  // shrink
+		{"baz", 9, true},  // 1+9 == 10
+		{"zot", 0, false}, // absent
+	}
+	for _, tt := range tests {
+		if got := c.ReplaceIfFits(tt.key, "new", tt.size, 60); got != tt.want {
+			t.Errorf("ReplaceIfFits(%s, %d) = %v, want %v", tt.key, tt.size, got, tt.want)
+		}
+	}
+
+	if c.Size() != 10 {
+		t.Errorf("size = %d, want %d", c.Size(), 10)
+	}
+	if c.Items() != 2 {
+		t.Errorf("items = %d, want %d", c.Items(), 2)
+	}
+	if st := c.Stats(); st.Evictions != 0 {
+		t.Errorf("evictions = %d, want 0", st.Evictions)
+	}
+	if _, ok := c.Get("zot"); ok {
+		t.Errorf("ReplaceIfFits should not add absent items")
+	}
+}
+
+func TestCacheReadOnly(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.RegisterTier("short", 30*time.Second)
+	c.Set("foo", "bar", 3, 60)
+	c.Set("baz", "qux", 3, 30)
+
+	c.SetReadOnly(true)
+
+	c.Set("foo", "new", 3, 60)
+	c.Set("zot", "bork", 4, 60)
+	if b := c.GetOrSet("bork", "bork", 4, 60); b != "bork" {
+		t.Errorf("read-only GetOrSet(bork) = %v, want bork", b)
+	}
+	if err := c.SetTier("zot", "bork", 4, "short"); err != ErrReadOnly {
+		t.Errorf("read-only SetTier() error = %v, want %v", err, ErrReadOnly)
+	}
+	if c.ReplaceIfFits("foo", "new", 3, 60) {
+		t.Errorf("read-only ReplaceIfFits(foo) should be rejected")
+	}
+	if c.Delete("foo") {
+		t.Errorf("read-only Delete(foo) should be rejected")
+	}
+	c.Clear()
+	c.ClearAll()
+
+	if v, ok := c.Get("foo"); !ok || v != "bar" {
+		t.Errorf("read-only Get(foo) = (%v, %v), want (bar, true)", v, ok)
+	}
+	if b := c.GetOrSet("foo", "new", 3, 60); b != "bar" {
+		t.Errorf("read-only GetOrSet(foo) = %v, want bar", b)
+	}
+	for _, k := range []string{"zot", "bork"} {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("read-only cache should not store %s", k)
+		}
+	}
+
+	// cleaners are paused
+	c.cleanAll(t0.Add(45 * time.Second))
+	c.clean(t0.Add(45 * time.Second))
+	if c.Items() != 2 || c.Size() != 6 {
+		t.Errorf("read-only items, size = %d, %d, want 2, 6", c.Items(), c.Size())
+	}
+
+	c.SetReadOnly(false)
+
+	c.cleanAll(t0.Add(45 * time.Second))
+	if c.Items() != 1 {
+		t.Errorf("items = %d, want 1", c.Items())
+	}
+	c.Set("zot", "bork", 4, 60)
+	if !c.Delete("foo") {
+		t.Errorf("Delete(foo) should be done")
+	}
+	if c.Delete("foo") {
+		t.Errorf("Delete(foo) should return false for absent key")
+	}
+	if _, ok := c.Get("foo"); ok {
+		t.Errorf("Get(foo) should be deleted")
+	}
+	if v, ok := c.Get("zot"); !ok || v != "bork" {
+		t.Errorf("Get(zot) = (%v, %v), want (bork, true)", v, ok)
+	}
+	if c.Items() != 1 || c.Size() != 4 {
+		t.Errorf("items, size = %d, %d, want 1, 4", c.Items(), c.Size())
+	}
+}
+
+func TestCacheGetOrSetRace(t *testing.T) {
+	c := New[string, int](0)
+
+	const workers = 64
+	var wg, wgStart sync.WaitGroup
+	results := make([]int, workers)
+	wgStart.Add(workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wgStart.Done()
+			wgStart.Wait()
+			results[i] = c.GetOrSet("foo", i, 1, 60)
+		}(i)
+	}
+	wg.Wait()
+
+	stored, ok := c.Get("foo")
+	if !ok {
+		t.Fatalf("cache.Get(foo) should be present")
+	}
+	for i, r := range results {
+		if r != stored {
+			t.Errorf("GetOrSet #%d = %d, want stored %d", i, r, stored)
+		}
+	}
+	if c.Items() != 1 || c.Size() != 1 {
+		t.Errorf("items, size = %d, %d, want 1, 1", c.Items(), c.Size())
+	}
+	// workers-1 GetOrSet hits and a Get hit
+	if st := c.Stats(); st.Misses != 1 || st.Hits != workers {
+		t.Errorf("stats = %+v, want 1 miss and %d hits", st, workers)
+	}
+}
+
+func TestCacheSetCtx(t *testing.T) {
+	c := New[string, string](10)
+	c.SetBlockWhenFull(true)
+
+	if err := c.SetCtx(context.Background(), "foo", "bar", 5, 60); err != nil {
+		t.Fatalf("SetCtx(foo) error = %v", err)
+	}
+	if err := c.SetCtx(context.Background(), "baz", "qux", 5, 60); err != nil {
+		t.Fatalf("SetCtx(baz) error = %v", err)
+	}
+
+	// full, cancelled by timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	err := c.SetCtx(ctx, "zot", "bork", 5, 60)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Errorf("SetCtx(zot) on full cache error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, ok := c.Get("zot"); ok {
+		t.Errorf("SetCtx(zot) on full cache should not store the item")
+	}
+	if st := c.Stats(); st.Evictions != 0 {
+		t.Errorf("evictions = %d, want 0", st.Evictions)
+	}
+
+	// wait for room
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.SetCtx(ctx, "zot", "bork", 5, 60)
+	}()
+	select {
+	case err = <-errc:
+		t.Fatalf("SetCtx(zot) should wait for room, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Delete("foo")
+	select {
+	case err = <-errc:
+		if err != nil {
+			t.Errorf("SetCtx(zot) error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("SetCtx(zot) should be done after Delete")
+	}
+	if v, ok := c.Get("zot"); !ok || v != "bork" {
+		t.Errorf("cache.Get(zot) = (%v, %v), want (bork, true)", v, ok)
+	}
+
+	// replace in place fits
+	if err = c.SetCtx(ctx, "zot", "bork", 5, 60); err != nil {
+		t.Errorf("SetCtx(zot) replace error = %v", err)
+	}
+
+	// evict when not blocking
+	c.SetBlockWhenFull(false)
+	if err = c.SetCtx(ctx, "foo", "bar", 5, 60); err != nil {
+		t.Errorf("SetCtx(foo) error = %v", err)
+	}
+	if st := c.Stats(); st.Evictions != 1 {
+		t.Errorf("evictions = %d, want 1", st.Evictions)
+	}
+}
+
+func TestCacheEntries(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, 30)
+	timeNow = func() time.Time { return t0.Add(10 * time.Second) }
+	c.Set("baz", "qux", 3, 60)
+	c.Set("zot", "bork", 4, 5)
+	c.Get("baz")
+	c.Get("baz")
+	c.Get("foo")
+
+	timeNow = func() time.Time { return t0.Add(20 * time.Second) }
+	entries := c.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	want := []EntrySnapshot[string, string]{
+		{Key: "baz", Value: "qux", Size: 3, Expires: t0.Add(70 * time.Second), Created: t0.Add(10 * time.Second), Hits: 2},
+		{Key: "foo", Value: "bar", Size: 3, Expires: t0.Add(30 * time.Second), Created: t0, Hits: 1},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("cache.Entries() = %+v, want %+v", entries, want)
+	}
+}
+
+func TestCacheSizeOverflow(t *testing.T) {
+	c := New[string, string](0)
+
+	c.Set("foo", "bar", math.MaxUint64-10, 60)
+	c.Set("baz", "qux", 100, 60)
+	if c.Size() != math.MaxUint64 {
+		t.Errorf("size = %d, want %d", c.Size(), uint64(math.MaxUint64))
+	}
+	if info, _ := c.Inspect("baz"); info.Size != 10 {
+		t.Errorf("capped size = %d, want %d", info.Size, 10)
+	}
+	c.Delete("foo")
+	if c.Size() != 10 {
+		t.Errorf("size after Delete = %d, want %d", c.Size(), 10)
+	}
+
+	// an overflowing item can't fit and is evicted instead of wrapping the total size
+	c = New[string, string](1000)
+	c.Set("foo", "bar", 500, 60)
+	c.Set("baz", "qux", math.MaxUint64, 60)
+	if _, ok := c.Get("baz"); ok {
+		t.Errorf("overflowing item should be evicted")
+	}
+	// foo may be evicted too before baz
+	if _, ok := c.Get("foo"); ok != (c.Size() == 500) || c.Items() > 1 {
+		t.Errorf("items, size = %d, %d, want foo only or empty", c.Items(), c.Size())
+	}
+
+	c.Set("foo", "bar", 500, 60)
+	if c.ReplaceIfFits("foo", "bar", math.MaxUint64, 60) {
+		t.Errorf("ReplaceIfFits() with an overflowing size should not fit")
+	}
+	if c.Size() != 500 {
+		t.Errorf("size = %d, want %d", c.Size(), 500)
+	}
+}
+
+func TestCacheRangeOrdered(t *testing.T) {
+	c := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, 1, 60)
+	}
+	// removals reorder the keys slice
+	for i := 0; i < 100; i += 3 {
+		c.Delete(i)
+	}
+	c.Set(1, 1, 1, 60) // overwrite keeps the position
+	c.Set(0, 0, 1, 60) // reinsert is appended
+	c.Set(2, 2, 1, 0)  // expired
+	c.Set(1000, 1000, 1, 60)
+	timeNow = func() time.Time { return t0.Add(time.Second) }
+
+	var want []int
+	for i := 1; i < 100; i++ {
+		if i%3 != 0 && i != 2 {
+			want = append(want, i)
+		}
+	}
+	want = append(want, 0, 1000)
+
+	var got []int
+	c.RangeOrdered(func(k, v int) bool {
+		got = append(got, k)
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.RangeOrdered() = %v, want %v", got, want)
+	}
+
+	got = got[:0]
+	c.RangeOrdered(func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	if !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("cache.RangeOrdered() stopped = %v, want %v", got, want[:3])
+	}
+}
+
+func TestCacheWorkSaved(t *testing.T) {
+	c := New[string, string](0)
+
+	c.Set("foo", "bar", 3, 60, WithSavings(100))
+	c.Set("baz", "qux", 3, 60, WithSavings(7))
+	c.Set("zot", "bork", 4, 60)
+
+	c.Get("foo")
+	c.Get("foo")
+	c.GetOrSet("baz", "new", 3, 60)
+	c.Get("zot")
+	c.Get("bork") // miss
+	c.ComputeIfAbsent("foo", 3, 60, func() (string, error) { return "new", nil })
+
+	if st := c.Stats(); st.WorkSaved != 3*100+7 {
+		t.Errorf("work saved = %d, want %d", st.WorkSaved, 3*100+7)
+	}
+	if st := c.StatsAndReset(); st.WorkSaved != 3*100+7 {
+		t.Errorf("work saved = %d, want %d", st.WorkSaved, 3*100+7)
+	}
+	if st := c.Stats(); st.WorkSaved != 0 {
+		t.Errorf("work saved after reset = %d, want 0", st.WorkSaved)
+	}
+}
+
+func TestCacheLatencySaved(t *testing.T) {
+	for _, c := range []*Cache[string, string]{New[string, string](0), NewCopyOnWrite[string, string](0)} {
+		c.Set("foo", "bar", 3, 60, WithSavedLatency(20*time.Millisecond))
+		c.Set("baz", "qux", 3, 60, WithSavedLatency(time.Second), WithSavings(7))
+		c.Set("zot", "bork", 4, 60)
+
+		c.Get("foo")
+		c.Get("foo")
+		c.GetOrSet("baz", "new", 3, 60)
+		c.Get("zot")
+		c.Get("bork") // miss
+
+		want := 2*20*time.Millisecond + time.Second
+		if st := c.StatsAndReset(); st.LatencySaved != want || st.WorkSaved != 7 {
+			t.Errorf("latency saved = %v, work saved %d, want %v, 7", st.LatencySaved, st.WorkSaved, want)
+		}
+		if st := c.Stats(); st.LatencySaved != 0 {
+			t.Errorf("latency saved after reset = %v, want 0", st.LatencySaved)
+		}
+	}
+}
+
+func TestCacheHugeExpire(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, math.MaxInt32)
+	c.RegisterTier("forever", math.MaxInt64)
+	if err := c.SetTier("baz", "qux", 3, "forever"); err != nil {
+		t.Fatalf("SetTier() error = %v", err)
+	}
+
+	if info, _ := c.Inspect("foo"); info.TTL != math.MaxInt32*time.Second {
+		t.Errorf("cache.Inspect(foo) TTL = %v, want %v", info.TTL, math.MaxInt32*time.Second)
+	}
+	if info, _ := c.Inspect("baz"); info.TTL != math.MaxInt64 {
+		t.Errorf("cache.Inspect(baz) TTL = %v, want %v", info.TTL, time.Duration(math.MaxInt64))
+	}
+
+	timeNow = func() time.Time { return t0.Add(60 * 365 * 24 * time.Hour) }
+	for _, k := range []string{"foo", "baz"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("cache.Get(%s) should be long-lived", k)
+		}
+	}
+	c.cleanAll(timeNow())
+	if c.Items() != 2 {
+		t.Errorf("items = %d, want 2", c.Items())
+	}
+}
+
+func TestCacheGetOrDefault(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, 10)
+	c.Set("old", "bar", 3, 1)
+
+	if v := c.GetOrDefault("foo", "def"); v != "bar" {
+		t.Errorf("cache.GetOrDefault(foo) = %q, want %q", v, "bar")
+	}
+	if v := c.GetOrDefault("baz", "def"); v != "def" {
+		t.Errorf("cache.GetOrDefault(baz) = %q, want %q", v, "def")
+	}
+
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+	if v := c.GetOrDefault("old", "def"); v != "def" {
+		t.Errorf("cache.GetOrDefault(old) = %q, want %q after expiry", v, "def")
+	}
+}
+
+func TestCacheSetIf(t *testing.T) {
+	c := New[string, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	newer := func(v int) func(existing int, existed bool) bool {
+		return func(existing int, existed bool) bool {
+			return !existed || v > existing
+		}
+	}
+
+	if !c.SetIf("foo", 2, 1, 10, newer(2)) {
+		t.Errorf("cache.SetIf(foo, 2) on absent key = false, want true")
+	}
+	if c.SetIf("foo", 1, 1, 10, newer(1)) {
+		t.Errorf("cache.SetIf(foo, 1) over 2 = true, want false")
+	}
+	if v, _ := c.Get("foo"); v != 2 {
+		t.Errorf("cache.Get(foo) = %d, want 2", v)
+	}
+	if !c.SetIf("foo", 3, 1, 1, newer(3)) {
+		t.Errorf("cache.SetIf(foo, 3) over 2 = false, want true")
+	}
+	if v, _ := c.Get("foo"); v != 3 {
+		t.Errorf("cache.Get(foo) = %d, want 3", v)
+	}
+
+	// an expired item counts as absent
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+	var existed bool
+	if !c.SetIf("foo", 0, 1, 10, func(_ int, ok bool) bool { existed = ok; return !ok }) || existed {
+		t.Errorf("cache.SetIf(foo) over expired item should see it as absent")
+	}
+	if v, _ := c.Get("foo"); v != 0 {
+		t.Errorf("cache.Get(foo) = %d, want 0", v)
+	}
+
+	c.SetReadOnly(true)
+	if c.SetIf("bar", 1, 1, 10, newer(1)) {
+		t.Errorf("cache.SetIf(bar) on read-only cache = true, want false")
+	}
+}
+
+func TestCacheReclaimed(t *testing.T) {
+	c := New[string, string](10)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("expired", "bar", 1, 1)
+	c.Set("deleted", "bar", 2, 10)
+	c.Set("evicted", "bar", 3, 10, WithPriority(PriorityLow))
+	c.Set("cleared", "bar", 4, 10, Sticky())
+
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+	c.cleanAll(timeNow())
+	c.Delete("deleted")
+	c.Set("big", "bar", 6, 10) // evicts "evicted"
+
+	if st := c.Stats(); st.Reclaimed != 6 {
+		t.Errorf("stats.Reclaimed = %d, want 6", st.Reclaimed)
+	}
+
+	c.ClearAll()
+	if st := c.StatsAndReset(); st.Reclaimed != 16 {
+		t.Errorf("stats.Reclaimed = %d, want 16", st.Reclaimed)
+	}
+	if st := c.Stats(); st.Reclaimed != 0 {
+		t.Errorf("stats.Reclaimed = %d after reset, want 0", st.Reclaimed)
+	}
+}
+
+func TestCacheAgePercentiles(t *testing.T) {
+	c := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	ps := []float64{0, 0.5, 0.9, 0.99, 1}
+
+	if got := c.AgePercentiles(ps); !reflect.DeepEqual(got, make([]time.Duration, len(ps))) {
+		t.Errorf("cache.AgePercentiles() of the empty cache = %v, want zeros", got)
+	}
+
+	// items 1..100 stored i seconds before now
+	for i := 1; i <= 100; i++ {
+		timeNow = func() time.Time { return t0.Add(-time.Duration(i) * time.Second) }
+		c.Set(i, i, 1, 1000)
+	}
+	// an expired item is excluded
+	timeNow = func() time.Time { return t0.Add(-time.Hour) }
+	c.Set(0, 0, 1, 1)
+	timeNow = func() time.Time { return t0 }
+
+	want := []time.Duration{time.Second, 50 * time.Second, 90 * time.Second, 99 * time.Second, 100 * time.Second}
+	if got := c.AgePercentiles(ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.AgePercentiles(%v) = %v, want %v", ps, got, want)
+	}
+}
+
+func TestCacheRefreshIfBelow(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", "bar", 3, 60)
+	c.Set("baz", "bar", 3, 10)
+
+	if c.RefreshIfBelow("foo", 30*time.Second, time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(foo) with 60s left = true, want false")
+	}
+	if !c.RefreshIfBelow("baz", 30*time.Second, time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(baz) with 10s left = false, want true")
+	}
+	if c.RefreshIfBelow("missing", 30*time.Second, time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(missing) = true, want false")
+	}
+	for _, k := range []string{"foo", "baz"} {
+		if info, _ := c.Inspect(k); info.TTL != time.Minute {
+			t.Errorf("cache.Inspect(%s) TTL = %v, want 1m", k, info.TTL)
+		}
+	}
+
+	timeNow = func() time.Time { return t0.Add(40 * time.Second) }
+	if !c.RefreshIfBelow("foo", 30*time.Second, time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(foo) with 20s left = false, want true")
+	}
+	if next, _ := c.NextExpiry(); !next.Equal(t0.Add(time.Minute)) {
+		t.Errorf("cache.NextExpiry() = %v, want the baz expiration %v", next, t0.Add(time.Minute))
+	}
+
+	// an expired item isn't refreshed
+	timeNow = func() time.Time { return t0.Add(2 * time.Minute) }
+	if c.RefreshIfBelow("baz", 30*time.Second, time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(baz) of the expired item = true, want false")
+	}
+}
+
+func TestCachePauseCleaner(t *testing.T) {
+	c := New[int, int](0)
+
+	sleeping := make(chan bool)
+	wake := make(chan bool)
+	cleanerSleep = func(_ time.Duration) {
+		sleeping <- true
+		<-wake
+	}
+	done := make(chan bool)
+	cleanerDone = func() { done <- true }
+
+	defer func() {
+		cleanerSleep = time.Sleep
+		cleanerDone = func() {}
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, 1, 30)
+	}
+
+	go c.Cleaner(5 * time.Minute)
+	run := func() {
+		<-sleeping
+		wake <- true
+		<-done
+	}
+
+	c.PauseCleaner()
+	timeNow = func() time.Time { return t0.Add(time.Minute) }
+	for n := 0; n < 3; n++ {
+		run()
+		if c.Items() != 10 {
+			t.Fatalf("items = %d while the cleaner is paused, want 10", c.Items())
+		}
+	}
+	if _, ok := c.Get(0); ok {
+		t.Errorf("cache.Get(0) of the expired item should miss while the cleaner is paused")
+	}
+
+	c.ResumeCleaner()
+	run()
+	if c.Items() != 0 {
+		t.Errorf("items = %d after the cleaner is resumed, want 0", c.Items())
+	}
+	// wait for the cleaner to go idle in the mocked sleep, so it doesn't run after the hooks are restored
+	<-sleeping
+}
+
+func TestCacheTTLBands(t *testing.T) {
+	c := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	// the first one is expired
+	for i, expire := range []int32{-1, 1, 59, 60, 300, 301, 3600, math.MaxInt32} {
+		c.Set(i, i, 1, expire)
+	}
+
+	soon, medium, later, never := c.TTLBands()
+	if soon != 2 || medium != 2 || later != 2 || never != 1 {
+		t.Errorf("cache.TTLBands() = %d, %d, %d, %d, want 2, 2, 2, 1", soon, medium, later, never)
+	}
+}
+
+func TestCacheExpireOlderThan(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	for i, k := range []string{"a", "b", "c", "d"} {
+		at := t0.Add(time.Duration(i) * time.Minute)
+		timeNow = func() time.Time { return at }
+		c.Set(k, k, 1, 3600)
+	}
+	// overwrite after the cutoff
+	c.Set("a", "a2", 1, 3600)
+
+	if n := c.ExpireOlderThan(t0.Add(2 * time.Minute)); n != 1 {
+		t.Errorf("cache.ExpireOlderThan() = %d, want 1", n)
+	}
+	for k, present := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if _, ok := c.Get(k); ok != present {
+			t.Errorf("cache.Get(%s) = %v, want %v", k, ok, present)
+		}
+	}
+	if n := c.ExpireOlderThan(t0.Add(time.Hour)); n != 3 || c.Items() != 0 {
+		t.Errorf("cache.ExpireOlderThan() = %d, items %d, want 3, 0", n, c.Items())
+	}
+}
+
+func TestCacheSwapWithOldTTL(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	if old, ttl, had := c.SwapWithOldTTL("token", "t1", 2, 60); had || old != "" || ttl != 0 {
+		t.Errorf("cache.SwapWithOldTTL(token) of the absent item = (%q, %v, %v), want (\"\", 0, false)", old, ttl, had)
+	}
+
+	timeNow = func() time.Time { return t0.Add(45 * time.Second) }
+	if old, ttl, had := c.SwapWithOldTTL("token", "t2", 2, 120); !had || old != "t1" || ttl != 15*time.Second {
+		t.Errorf("cache.SwapWithOldTTL(token) = (%q, %v, %v), want (%q, 15s, true)", old, ttl, had, "t1")
+	}
+	if info, _ := c.Inspect("token"); info.Value != "t2" || info.TTL != 2*time.Minute {
+		t.Errorf("cache.Inspect(token) = %q with TTL %v, want %q with TTL 2m", info.Value, info.TTL, "t2")
+	}
+
+	// an expired item counts as absent
+	timeNow = func() time.Time { return t0.Add(time.Hour) }
+	if _, _, had := c.SwapWithOldTTL("token", "t3", 2, 60); had {
+		t.Errorf("cache.SwapWithOldTTL(token) over the expired item reports it")
+	}
+}
+
+func TestCacheTrimIdleExpired(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+	c.Set("idle", "idle", 1, 60)
+	c.Set("stale", "stale", 1, 60)
+	c.Set("hot", "hot", 1, 240)
+	c.Set("live", "live", 1, 3600)
+
+	timeNow = func() time.Time { return t0.Add(30 * time.Second) }
+	c.Get("stale")
+	timeNow = func() time.Time { return t0.Add(230 * time.Second) }
+	c.Get("hot")
+
+	// all but live are expired, hot was accessed within the idle time
+	timeNow = func() time.Time { return t0.Add(5 * time.Minute) }
+	if n := c.TrimIdleExpired(2 * time.Minute); n != 2 {
+		t.Errorf("cache.TrimIdleExpired() = %d, want 2", n)
+	}
+	for k, present := range map[string]bool{"idle": false, "stale": false, "hot": true, "live": true} {
+		if _, ok := c.get(k); ok != present {
+			t.Errorf("cache item %s present = %v, want %v", k, ok, present)
+		}
+	}
+}
+
+func TestCacheReserve(t *testing.T) {
+	c := New[string, string](100)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	for i := 0; i < 10; i++ {
+		at := t0.Add(time.Duration(i) * time.Second)
+		timeNow = func() time.Time { return at }
+		c.Set(fmt.Sprintf("key%d", i), "v", 10, 60)
+	}
+
+	if n := c.Reserve(0, 5); n != 0 {
+		t.Errorf("cache.Reserve(0) = %d, want 0", n)
+	}
+	if n := c.Reserve(25, 3); n != 3 || c.Size() != 70 {
+		t.Errorf("cache.Reserve(25) = %d, size %d, want 3, 70", n, c.Size())
+	}
+	for i := 0; i < 10; i++ {
+		k := fmt.Sprintf("key%d", i)
+		if _, ok := c.Get(k); ok != (i >= 3) {
+			t.Errorf("cache.Get(%s) = %v, want %v", k, ok, i >= 3)
+		}
+	}
+	if st := c.Stats(); st.Evictions != 3 {
+		t.Errorf("evictions = %d, want 3", st.Evictions)
+	}
+	// enough room already
+	if n := c.Reserve(30, 3); n != 0 {
+		t.Errorf("cache.Reserve(30) = %d, want 0", n)
+	}
+	// the batch is stored without evictions
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("batch%d", i), "v", 10, 60)
+	}
+	if st := c.Stats(); st.Evictions != 3 || c.Size() != 100 {
+		t.Errorf("evictions = %d, size %d, want 3, 100", st.Evictions, c.Size())
+	}
+	if n := c.Reserve(1000, 1); n != 10 || c.Items() != 0 {
+		t.Errorf("cache.Reserve(1000) = %d, items %d, want 10, 0", n, c.Items())
+	}
+}
+
+func TestCacheDeleteIf(t *testing.T) {
+	c := New[string, string](0)
+	c.Set("job", "running", 1, 60)
+	c.Set("dep", "x", 1, 60, c.DependsOn("job"))
+	c.Set("old", "done", 1, -1) // expired
+
+	done := func(v string) bool { return v == "done" }
+	if c.DeleteIf("job", done) {
+		t.Errorf("cache.DeleteIf(job) of the running job = true, want false")
+	}
+	if c.DeleteIf("old", done) || c.DeleteIf("missing", done) {
+		t.Errorf("cache.DeleteIf() of the expired or missing item = true, want false")
+	}
+	c.Set("job", "done", 1, 60)
+	if !c.DeleteIf("job", done) {
+		t.Errorf("cache.DeleteIf(job) of the done job = false, want true")
+	}
+	if _, ok := c.Get("dep"); ok {
+		t.Errorf("cache.Get(dep) of the dependent item should miss")
+	}
+
+	// the condition is checked against the current value: only even values are deleted, each at most once
+	const rounds = 10000
+	c2 := New[string, int](0)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			c2.Set("k", i, 1, 60)
+		}
+	}()
+	deleted := make(map[int]bool)
+	for i := 0; i < rounds; i++ {
+		var seen int
+		if c2.DeleteIf("k", func(v int) bool { seen = v; return v%2 == 0 }) {
+			if seen%2 != 0 || deleted[seen] {
+				t.Fatalf("cache.DeleteIf(k) deleted %d, want an even value deleted once", seen)
+			}
+			deleted[seen] = true
+		}
+	}
+	wg.Wait()
+	if v, ok := c2.Get("k"); !ok && !deleted[rounds-1] {
+		t.Errorf("cache.Get(k) of the last value %d should be present", rounds-1)
+	} else if ok && v != rounds-1 {
+		t.Errorf("cache.Get(k) = %d, want %d", v, rounds-1)
+	}
+}
+
+func TestCacheEffectiveTTL(t *testing.T) {
+	c := New[string, string](0)
+	tests := []struct {
+		expire int64
+		want   time.Duration
+	}{
+		{expire: 60, want: time.Minute},
+		{expire: 0, want: 0},
+		{expire: -1, want: -time.Second},
+		{expire: math.MaxInt32 + 1, want: math.MaxInt32 * time.Second},
+		{expire: math.MinInt64, want: math.MinInt32 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := c.EffectiveTTL("k", tt.expire); got != tt.want {
+			t.Errorf("cache.EffectiveTTL(k, %d) = %v, want %v", tt.expire, got, tt.want)
+		}
+	}
+
+	// the stored TTL matches
+	defer func() {
+		timeNow = time.Now
+	}()
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+	ttl := c.EffectiveTTL("k", 3600)
+	c.Set("k", "v", 1, 3600)
+	if info, ok := c.Inspect("k"); !ok || info.TTL != ttl {
+		t.Errorf("cache.Inspect(k) TTL = %v, want %v", info.TTL, ttl)
+	}
+}
+
+func TestCacheRotate(t *testing.T) {
+	c := New[string, int](0)
+	c.Set("foo", 1, 1, 60)
+	c.Set("bar", 2, 1, 60, Sticky())
+	c.Set("old", 3, 1, -1) // expired
+	if items := c.Rotate(); !reflect.DeepEqual(items, map[string]int{"foo": 1, "bar": 2}) {
+		t.Errorf("cache.Rotate() = %v, want the unexpired items", items)
+	}
+	if c.Items() != 0 || c.Size() != 0 {
+		t.Errorf("items = %d (size %d) after the rotation, want 0", c.Items(), c.Size())
+	}
+	if items := c.Rotate(); len(items) != 0 {
+		t.Errorf("cache.Rotate() of the empty cache = %v, want empty", items)
+	}
+
+	// counters incremented during the rotations are counted in exactly one window
+	counters := New[int, int64](0)
+	const (
+		writers    = 4
+		increments = 10000
+	)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				IncrementWindow(counters, i%10, 1, 3600)
+			}
+		}()
+	}
+	var (
+		total   int64
+		windows int
+	)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for rotated := false; !rotated; windows++ {
+		select {
+		case <-done:
+			rotated = true
+		default:
+		}
+		for _, count := range counters.Rotate() {
+			total += count
+		}
+	}
+	if total != writers*increments {
+		t.Errorf("counted %d increments in %d windows, want %d", total, windows, writers*increments)
+	}
+}
+
+func TestCacheExpireInt64(t *testing.T) {
+	c := New[string, string](0)
+	// int64 expiration times beyond the int32 range are clamped, not wrapped around
+	if !c.SetIf("foo", "bar", 1, math.MaxInt32+1, func(string, bool) bool { return true }) {
+		t.Fatalf("cache.SetIf(foo) should store the item")
+	}
+	if info, _ := c.Inspect("foo"); info.TTL <= 0 {
+		t.Errorf("cache.Inspect(foo) TTL = %v, want clamped to %v", info.TTL, time.Duration(math.MaxInt32)*time.Second)
+	}
+	if _, _, err := c.ComputeIfAbsent("baz", 1, math.MinInt64, func() (string, error) { return "qux", nil }); err != nil {
+		t.Fatalf("cache.ComputeIfAbsent(baz) error = %v", err)
+	}
+	if _, ok := c.Get("baz"); ok {
+		t.Errorf("cache.Get(baz) of the item stored expired should miss")
+	}
+}
+
 func random(min, max int) int {
 	return rand.Intn(max-min) + min
 }
@@ -144,7 +1605,7 @@ type kv struct {
 }
 
 func Benchmark(b *testing.B) {
-	c := &Cache[string, string]{cache: make(map[string]element[string])}
+	c := &Cache[string, string]{cache: make(mapStore[string, string])}
 	vals := []kv{
 		{"1", "string 1"}, {"2", "string 2"}, {"3", "string 3"}, {"4", "string 4"},
 		{"10", "string 10"}, {"100", "string 100"}, {"1000", "string 1000"}, {"10000", "string 10000"},
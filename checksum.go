@@ -0,0 +1,25 @@
+package expirecache
+
+import "hash/fnv"
+
+// Checksum returns a checksum of the unexpired items (keys, values and sizes), for verifying replicas or
+// snapshot round-trips: caches with the same unexpired items have the same checksum regardless of the insertion order.
+// Items are encoded like by Key, so the values with pointers are compared by the addresses.
+func (ec *Cache[K, T]) Checksum() uint64 {
+	now := timeNow()
+	var sum uint64
+	h := fnv.New64a()
+	ec.RLock()
+	for _, k := range ec.keys {
+		v := ec.elem(k)
+		if !ec.alive(k, v, now) {
+			continue
+		}
+		h.Reset()
+		h.Write([]byte(Key(k, v.data, v.size)))
+		// the items hashes are summed, so the order doesn't matter
+		sum += mix64(h.Sum64())
+	}
+	ec.RUnlock()
+	return sum
+}
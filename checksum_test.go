@@ -0,0 +1,66 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheChecksum(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	a := New[string, snapshotValue](0)
+	b := New[string, snapshotValue](0)
+	if a.Checksum() != b.Checksum() {
+		t.Errorf("empty caches checksums differ")
+	}
+	for i, k := range []string{"foo", "bar", "baz"} {
+		a.Set(k, snapshotValue{Name: k, Count: i}, uint64(i), 60)
+	}
+	// reversed order, an expired item and an overwrite
+	b.Set("expired", snapshotValue{Name: "expired"}, 1, 0)
+	b.Set("baz", snapshotValue{Name: "baz"}, 2, 60)
+	for i, k := range []string{"baz", "bar", "foo"} {
+		b.Set(k, snapshotValue{Name: k, Count: 2 - i}, uint64(2-i), 30)
+	}
+	timeNow = func() time.Time { return t0.Add(time.Second) }
+
+	sum := a.Checksum()
+	if b.Checksum() != sum {
+		t.Errorf("checksums of the same items differ: %x, %x", sum, b.Checksum())
+	}
+
+	// snapshot round-trip
+	var buf maxWriter
+	if err := a.SaveToWriter(&buf); err != nil {
+		t.Fatalf("SaveToWriter() error = %v", err)
+	}
+	c := New[string, snapshotValue](0)
+	if _, err := c.LoadFromReader(&buf); err != nil {
+		t.Fatalf("LoadFromReader() error = %v", err)
+	}
+	if c.Checksum() != sum {
+		t.Errorf("checksum of the loaded snapshot = %x, want %x", c.Checksum(), sum)
+	}
+
+	for name, change := range map[string]func(){
+		"value": func() { b.Set("foo", snapshotValue{Name: "foo", Count: 1}, 0, 60) },
+		"size":  func() { b.Set("foo", snapshotValue{Name: "foo"}, 1, 60) },
+		"key":   func() { b.Delete("foo"); b.Set("qux", snapshotValue{Name: "foo"}, 0, 60) },
+		"extra": func() { b.Set("qux", snapshotValue{}, 0, 60) },
+	} {
+		change()
+		if b.Checksum() == sum {
+			t.Errorf("checksum after the %s change = %x, want it changed", name, sum)
+		}
+		b.Delete("qux")
+		b.Set("foo", snapshotValue{Name: "foo"}, 0, 60)
+		if b.Checksum() != sum {
+			t.Errorf("checksum after the %s change reverted = %x, want %x", name, b.Checksum(), sum)
+		}
+	}
+}
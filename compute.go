@@ -0,0 +1,148 @@
+package expirecache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLoaderPanic is wrapped by the error returned for a panicking loader
+var ErrLoaderPanic = errors.New("expirecache: loader panic")
+
+// call is an in-flight computation of an item, shared by concurrent callers for the key
+type call[T any] struct {
+	done chan struct{}
+	v    T
+	err  error
+}
+
+// ComputeIfAbsent returns the unexpired item from the cache or computes it with fn and stores it
+// with the estimated size and expiration time in seconds. Concurrent callers for the same absent key
+// wait for a single fn call and get its result. fn is called with no lock held, the bool result reports
+// if fn was called by this caller. An fn error is returned (to all waiters) and nothing is stored.
+// A panic in fn is recovered and returned to all waiters as an error wrapping ErrLoaderPanic.
+func (ec *Cache[K, T]) ComputeIfAbsent(k K, size uint64, expire int64, fn func() (T, error)) (T, bool, error) {
+	return ec.computeIfAbsent(k, func() (T, uint64, int32, error) {
+		v, err := fn()
+		return v, size, clampExpire(expire), err
+	})
+}
+
+// computeIfAbsent is ComputeIfAbsent with the size and the expiration time returned by load
+func (ec *Cache[K, T]) computeIfAbsent(k K, load func() (T, uint64, int32, error)) (T, bool, error) {
+	// hits don't block the other readers
+	now := timeNow()
+	ec.RLock()
+	if v, ok := ec.get(k); ok && !ec.closed && ec.alive(k, v, now) {
+		promote := ec.touch(v, now)
+		ec.RUnlock()
+		if promote {
+			ec.promote(k, v)
+		}
+		ec.lookup(k, true)
+		return v.data, false, nil
+	}
+	ec.RUnlock()
+
+	for {
+		now := timeNow()
+		ec.Lock()
+		if ec.closed {
+			ec.Unlock()
+			var zero T
+			return zero, false, ErrClosed
+		}
+		if v, ok := ec.get(k); ok && ec.alive(k, v, now) {
+			if ec.touch(v, now) {
+				ec.extend(k, v)
+			}
+			ec.Unlock()
+			ec.lookup(k, true)
+			return v.data, false, nil
+		}
+		if c, ok := ec.inflight[k]; ok {
+			ec.Unlock()
+			// a miss, though the computation is shared
+			ec.lookup(k, false)
+			<-c.done
+			return c.v, false, c.err
+		}
+		if ec.maxInflight > 0 && len(ec.inflight) >= ec.maxInflight {
+			if !ec.inflightBlock {
+				ec.Unlock()
+				ec.lookup(k, false)
+				return ec.compute(k, load, nil)
+			}
+			if ec.inflightFreed == nil {
+				ec.inflightFreed = make(chan struct{})
+			}
+			freed := ec.inflightFreed
+			ec.Unlock()
+			<-freed
+			continue
+		}
+		c := &call[T]{done: make(chan struct{})}
+		if ec.inflight == nil {
+			ec.inflight = make(map[K]*call[T])
+		}
+		ec.inflight[k] = c
+		ec.Unlock()
+		ec.lookup(k, false)
+
+		return ec.compute(k, load, c)
+	}
+}
+
+// compute calls load and stores the result, c is the in-flight call registered for k (nil if deduplication is bypassed)
+func (ec *Cache[K, T]) compute(k K, load func() (T, uint64, int32, error), c *call[T]) (T, bool, error) {
+	var (
+		size   uint64
+		expire int32
+	)
+	v, err := safeCall(func() (v T, err error) {
+		v, size, expire, err = load()
+		return v, err
+	})
+
+	ec.Lock()
+	if c != nil {
+		delete(ec.inflight, k)
+		if ec.inflightFreed != nil {
+			close(ec.inflightFreed)
+			ec.inflightFreed = nil
+		}
+	}
+	if err == nil && !ec.readOnly && ec.allowSet() {
+		ec.actualSet(k, v, size, ec.ttl(expire))
+	}
+	ec.unlock()
+	if c != nil {
+		c.v, c.err = v, err
+		close(c.done)
+	}
+
+	return v, true, err
+}
+
+// SetMaxInflight limits the number of concurrent ComputeIfAbsent computations (0 for unlimited), so memory
+// stays bounded during a miss storm of distinct keys. When the limit is reached, new computations wait
+// for a free slot if block is true, otherwise they run without deduplication.
+func (ec *Cache[K, T]) SetMaxInflight(n int, block bool) {
+	ec.Lock()
+	ec.maxInflight = n
+	ec.inflightBlock = block
+	if ec.inflightFreed != nil {
+		close(ec.inflightFreed)
+		ec.inflightFreed = nil
+	}
+	ec.Unlock()
+}
+
+// safeCall calls fn, converting a panic into an error
+func safeCall[T any](fn func() (T, error)) (v T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrLoaderPanic, r)
+		}
+	}()
+	return fn()
+}
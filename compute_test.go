@@ -0,0 +1,223 @@
+package expirecache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheComputeIfAbsent(t *testing.T) {
+	c := New[string, int](0)
+
+	const workers = 32
+	var (
+		calls        int32
+		wg, wgStart  sync.WaitGroup
+		ran          int32
+		values       = make([]int, workers)
+		release      = make(chan struct{})
+		firstCompute = make(chan struct{})
+		firstOnce    sync.Once
+	)
+	fn := func() (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		firstOnce.Do(func() { close(firstCompute) })
+		<-release
+		return int(n) * 100, nil
+	}
+
+	wgStart.Add(workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wgStart.Done()
+			wgStart.Wait()
+			v, computed, err := c.ComputeIfAbsent("foo", 1, 60, fn)
+			if err != nil {
+				t.Errorf("ComputeIfAbsent() error = %v", err)
+			}
+			if computed {
+				atomic.AddInt32(&ran, 1)
+			}
+			values[i] = v
+		}(i)
+	}
+	<-firstCompute
+	close(release)
+	wg.Wait()
+
+	if calls != 1 || ran != 1 {
+		t.Errorf("fn calls = %d, computed = %d, want 1, 1", calls, ran)
+	}
+	for i, v := range values {
+		if v != 100 {
+			t.Errorf("ComputeIfAbsent() #%d = %d, want 100", i, v)
+		}
+	}
+
+	// present, fn isn't called
+	v, computed, err := c.ComputeIfAbsent("foo", 1, 60, func() (int, error) {
+		t.Errorf("fn should not be called for the present key")
+		return 0, nil
+	})
+	if v != 100 || computed || err != nil {
+		t.Errorf("ComputeIfAbsent() present = (%v, %v, %v), want (100, false, nil)", v, computed, err)
+	}
+
+	// errors aren't cached
+	errFn := errors.New("compute failed")
+	if _, computed, err = c.ComputeIfAbsent("bar", 1, 60, func() (int, error) {
+		return 0, errFn
+	}); !computed || err != errFn {
+		t.Errorf("ComputeIfAbsent() error = (%v, %v), want (true, %v)", computed, err, errFn)
+	}
+	if _, ok := c.Get("bar"); ok {
+		t.Errorf("failed computation should not be stored")
+	}
+	if len(c.inflight) != 0 {
+		t.Errorf("in-flight calls = %d, want 0", len(c.inflight))
+	}
+}
+
+func TestCacheComputeIfAbsentWaiterMiss(t *testing.T) {
+	c := New[string, int](0)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go c.ComputeIfAbsent("foo", 1, 60, func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, computed, err := c.ComputeIfAbsent("foo", 1, 60, func() (int, error) { return 2, nil }); v != 1 || computed || err != nil {
+			t.Errorf("ComputeIfAbsent() waiter = (%v, %v, %v), want (1, false, nil)", v, computed, err)
+		}
+	}()
+	// the waiter is counted before waiting
+	for c.Stats().Misses != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+
+	if st := c.Stats(); st.Hits != 0 || st.Misses != 2 {
+		t.Errorf("stats hits, misses = %d, %d, want 0, 2", st.Hits, st.Misses)
+	}
+	if v, computed, _ := c.ComputeIfAbsent("foo", 1, 60, nil); v != 1 || computed {
+		t.Errorf("ComputeIfAbsent() present = (%v, %v), want (1, false)", v, computed)
+	}
+	if st := c.Stats(); st.Hits != 1 {
+		t.Errorf("stats hits = %d, want 1", st.Hits)
+	}
+}
+
+func TestCacheComputeIfAbsentPanic(t *testing.T) {
+	c := New[string, int](0)
+
+	const workers = 8
+	var (
+		wg      sync.WaitGroup
+		started = make(chan struct{})
+		release = make(chan struct{})
+		errs    = make([]error, workers)
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, errs[0] = c.ComputeIfAbsent("foo", 1, 60, func() (int, error) {
+			close(started)
+			<-release
+			panic("loader failed")
+		})
+	}()
+	<-started
+	for i := 1; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = c.ComputeIfAbsent("foo", 1, 60, func() (int, error) {
+				return 1, nil
+			})
+		}(i)
+	}
+	// let the waiters block on the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		// waiters started after the panic may compute successfully
+		if err != nil && !errors.Is(err, ErrLoaderPanic) {
+			t.Errorf("ComputeIfAbsent() #%d error = %v, want %v", i, err, ErrLoaderPanic)
+		}
+	}
+	if !errors.Is(errs[0], ErrLoaderPanic) {
+		t.Errorf("ComputeIfAbsent() panicking error = %v, want %v", errs[0], ErrLoaderPanic)
+	}
+
+	// the cache is usable for the same key
+	c.Delete("foo")
+	v, computed, err := c.ComputeIfAbsent("foo", 1, 60, func() (int, error) { return 42, nil })
+	if v != 42 || !computed || err != nil {
+		t.Errorf("ComputeIfAbsent() after panic = (%v, %v, %v), want (42, true, nil)", v, computed, err)
+	}
+}
+
+func TestCacheComputeIfAbsentMaxInflight(t *testing.T) {
+	for _, block := range []bool{true, false} {
+		c := New[int, int](0)
+		c.SetMaxInflight(4, block)
+
+		const keys = 64
+		var (
+			wg          sync.WaitGroup
+			mu          sync.Mutex
+			maxInflight int
+			running     int32
+			maxRunning  int32
+		)
+		for i := 0; i < keys; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				v, _, err := c.ComputeIfAbsent(i, 1, 60, func() (int, error) {
+					n := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					c.RLock()
+					inflight := len(c.inflight)
+					c.RUnlock()
+					mu.Lock()
+					if n > maxRunning {
+						maxRunning = n
+					}
+					if inflight > maxInflight {
+						maxInflight = inflight
+					}
+					mu.Unlock()
+					time.Sleep(time.Millisecond)
+					return i * 10, nil
+				})
+				if err != nil || v != i*10 {
+					t.Errorf("block=%v: ComputeIfAbsent(%d) = (%d, %v), want %d", block, i, v, err, i*10)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if maxInflight > 4 {
+			t.Errorf("block=%v: max in-flight = %d, want <= 4", block, maxInflight)
+		}
+		if block && maxRunning > 4 {
+			t.Errorf("block=%v: max running = %d, want <= 4", block, maxRunning)
+		}
+		if c.Items() != keys {
+			t.Errorf("block=%v: items = %d, want %d", block, c.Items(), keys)
+		}
+	}
+}
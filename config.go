@@ -0,0 +1,131 @@
+package expirecache
+
+import "time"
+
+// Config is a snapshot of the cache settings, see Cache.Config
+type Config struct {
+	MaxSize         uint64        // 0 for unlimited
+	MaxEntries      int           // 0 for unlimited, see New2Q
+	CleanerInterval time.Duration // of the last started cleaner, 0 if none
+	CleanerPaused   bool
+	Stopped         bool
+	CloseOnStop     bool
+	SetRateLimit    float64 // Sets per second, 0 for unlimited
+	SetBurst        int
+	ReadOnly        bool
+	CopyOnWrite     bool // see NewCopyOnWrite
+	BlockWhenFull   bool
+	Tiers           map[string]time.Duration // a copy of the registered tiers
+
+	// eviction policy, a random victim (or the LRU of the lowest band with mixed priorities) by default
+	TwoQueue       bool // see New2Q
+	Admission      bool // the TinyLFU admission filter, see SetAdmission
+	Shrink         bool // items are shrunk before evicting, see SetShrinkFunc
+	VetoEvict      bool
+	Interning      bool // see SetValueInterning
+	ThrashTracking bool
+
+	GracePeriod      time.Duration
+	HotPromotionHits uint64
+	HotPromotionTTL  time.Duration
+	TTLBoostBelow    float64 // see SetTTLBoost
+	TTLBoostFactor   float64
+	TTLBoostMax      time.Duration
+	MaxInflight      int
+	InflightBlock    bool
+	MaxTags          int
+	DropLeastUsedTag bool
+	TagLimits        map[string]int // a copy of the per-tag limits
+
+	SizeFunc       bool // a custom SetAuto item size estimation
+	OnSpill        bool
+	SpillExpired   bool
+	Loaders        int // the number of GetOrLoad loaders
+	BatchSource    bool
+	SnapshotCodecs int  // the number of the per-item snapshot formats, see SetSnapshotCodecs
+	LowHitRate     bool // a callback, see SetOnLowHitRate
+	LatencyRecords bool // a recorder, see SetLatencyRecorder
+	SlowOps        bool // a callback, see SetOnSlowOp
+	MissTracking   int  // the tracked keys limit, see SetMissTracking
+	UniqueKeys     int  // the estimation precision, see SetUniqueKeysTracking
+	StatsWindows   int  // the retained windows of the last started StatsHistorian, 0 if none
+}
+
+// Config returns a snapshot of the cache settings, for diagnostics.
+func (ec *Cache[K, T]) Config() Config {
+	hr, _ := ec.hitRate.Load().(*hitRateTracker)
+	mt, _ := ec.misses.Load().(*missTracker[K])
+	u, _ := ec.unique.Load().(*uniqueKeys[K])
+	so, _ := ec.slowOps.Load().(*slowOpWatcher)
+	ec.RLock()
+	cfg := Config{
+		MaxSize:          ec.maxSize,
+		MaxEntries:       ec.maxEntries,
+		TwoQueue:         ec.twoQ != nil,
+		CleanerInterval:  ec.cleanerInterval,
+		CleanerPaused:    ec.cleanerPaused,
+		Stopped:          ec.stopped,
+		CloseOnStop:      ec.closeOnStop,
+		ReadOnly:         ec.readOnly,
+		CopyOnWrite:      ec.cow != nil,
+		BlockWhenFull:    ec.blockWhenFull,
+		Admission:        ec.admission != nil,
+		Shrink:           ec.shrink != nil,
+		VetoEvict:        ec.vetoEvict != nil,
+		Interning:        ec.internID != nil,
+		ThrashTracking:   ec.thrash != nil,
+		GracePeriod:      ec.grace,
+		HotPromotionHits: ec.promoteHits,
+		HotPromotionTTL:  ec.promoteTTL,
+		TTLBoostBelow:    ec.boostBelow,
+		TTLBoostFactor:   ec.boostFactor,
+		TTLBoostMax:      ec.boostMax,
+		MaxInflight:      ec.maxInflight,
+		InflightBlock:    ec.inflightBlock,
+		MaxTags:          ec.maxTags,
+		DropLeastUsedTag: ec.dropLeastUsedTag,
+		SizeFunc:         ec.sizeFunc != nil,
+		OnSpill:          ec.onSpill != nil,
+		SpillExpired:     ec.onSpill != nil && ec.spillExpired,
+		Loaders:          len(ec.loaders),
+		BatchSource:      ec.batchSource != nil,
+		SnapshotCodecs:   len(ec.snapshotCodecs),
+		LowHitRate:       hr != nil,
+		LatencyRecords:   ec.latencyRecorder() != nil,
+		SlowOps:          so != nil,
+	}
+	if ec.setLimit != nil {
+		cfg.SetRateLimit = ec.setLimit.rate
+		cfg.SetBurst = int(ec.setLimit.burst)
+	}
+	if mt != nil {
+		cfg.MissTracking = mt.size
+	}
+	if u != nil {
+		cfg.UniqueKeys = int(u.precision)
+	}
+	if ec.history != nil {
+		cfg.StatsWindows = cap(ec.history.windows)
+	}
+	if len(ec.tiers) > 0 {
+		cfg.Tiers = make(map[string]time.Duration, len(ec.tiers))
+		for name, ttl := range ec.tiers {
+			cfg.Tiers[name] = ttl
+		}
+	}
+	if len(ec.tagLimits) > 0 {
+		cfg.TagLimits = make(map[string]int, len(ec.tagLimits))
+		for tag, max := range ec.tagLimits {
+			cfg.TagLimits[tag] = max
+		}
+	}
+	ec.RUnlock()
+	return cfg
+}
+
+// startCleaner records the interval of a started cleaner
+func (ec *Cache[K, T]) startCleaner(d time.Duration) {
+	ec.Lock()
+	ec.cleanerInterval = d
+	ec.Unlock()
+}
@@ -0,0 +1,63 @@
+package expirecache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheConfig(t *testing.T) {
+	c := New[string, string](1000)
+	if cfg := c.Config(); !reflect.DeepEqual(cfg, Config{MaxSize: 1000}) {
+		t.Errorf("cache.Config() of the new cache = %+v, want only MaxSize", cfg)
+	}
+
+	exit := make(chan struct{})
+	close(exit)
+	c.StoppableApproximateCleaner(time.Minute, exit)
+	c.RegisterTier("short", time.Second)
+	c.SetAdmission(func(k string) uint64 { return uint64(len(k)) }, 100)
+	c.SetGracePeriod(time.Second)
+	c.SetHotPromotion(10, time.Minute)
+	c.SetMaxInflight(5, true)
+	c.SetMaxTags(100, true)
+	c.SetTagLimit("x", 3)
+	c.SetOnSpill(func(k, v string) {}, true)
+	c.RegisterLoader("user:", func(k string) (string, uint64, int32, error) { return k, 1, 60, nil })
+	c.SetMissTracking(64)
+	c.SetReadOnly(true)
+
+	want := Config{
+		MaxSize:          1000,
+		CleanerInterval:  time.Minute,
+		ReadOnly:         true,
+		Tiers:            map[string]time.Duration{"short": time.Second},
+		Admission:        true,
+		GracePeriod:      time.Second,
+		HotPromotionHits: 10,
+		HotPromotionTTL:  time.Minute,
+		MaxInflight:      5,
+		InflightBlock:    true,
+		MaxTags:          100,
+		DropLeastUsedTag: true,
+		TagLimits:        map[string]int{"x": 3},
+		OnSpill:          true,
+		SpillExpired:     true,
+		Loaders:          1,
+		MissTracking:     64,
+	}
+	cfg := c.Config()
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("cache.Config() = %+v, want %+v", cfg, want)
+	}
+
+	// a snapshot
+	cfg.Tiers["short"] = time.Hour
+	if c.Config().Tiers["short"] != time.Second {
+		t.Errorf("cache.Config() tiers aren't a copy")
+	}
+
+	if cfg := NewCopyOnWrite[string, string](0).Config(); !cfg.CopyOnWrite {
+		t.Errorf("cache.Config() of the copy-on-write cache = %+v, want CopyOnWrite", cfg)
+	}
+}
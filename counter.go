@@ -0,0 +1,33 @@
+package expirecache
+
+import "time"
+
+// counterSize is the estimated size of an IncrementWindow counter
+const counterSize = 8
+
+// IncrementWindow adds delta to the counter for the key, for fixed window rate limiting.
+// The first increment (or the first one after the window expiration) creates the counter with the
+// window time to live in seconds, next increments within the window keep the expiration time.
+// It returns the counter value and the window start time.
+// If the cache is read-only, the counter isn't changed (or created). Over the Set rate limit (see WithSetRateLimit)
+// a new counter isn't created and the zero count is returned, the increments of an existing one aren't limited.
+func IncrementWindow[K comparable](ec *Cache[K, int64], k K, delta int64, window int64) (count int64, windowStart time.Time) {
+	now := timeNow()
+	ec.Lock()
+	v, ok := ec.get(k)
+	if ok && ec.alive(k, v, now) {
+		if !ec.readOnly {
+			v = ec.mutate(k, v, func(v *element[int64]) { v.data += delta })
+		}
+		count, windowStart = v.data, v.created
+		ec.Unlock()
+		return count, windowStart
+	}
+	if ec.readOnly || !ec.allowSet() {
+		ec.Unlock()
+		return 0, time.Time{}
+	}
+	ec.actualSet(k, delta, counterSize, time.Duration(clampExpire(window))*time.Second)
+	ec.unlock()
+	return delta, now
+}
@@ -0,0 +1,82 @@
+package expirecache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrementWindow(t *testing.T) {
+	c := New[string, int64](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	const (
+		workers = 16
+		ops     = 1000
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < ops; n++ {
+				if _, start := IncrementWindow(c, "foo", 1, 60); !start.Equal(t0) {
+					t.Errorf("IncrementWindow() window start = %v, want %v", start, t0)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	timeNow = func() time.Time { return t0.Add(30 * time.Second) }
+	count, start := IncrementWindow(c, "foo", 2, 60)
+	if count != workers*ops+2 || !start.Equal(t0) {
+		t.Errorf("IncrementWindow() = (%d, %v), want (%d, %v)", count, start, workers*ops+2, t0)
+	}
+
+	// the window doesn't slide with increments
+	t1 := t0.Add(61 * time.Second)
+	timeNow = func() time.Time { return t1 }
+	count, start = IncrementWindow(c, "foo", 1, 60)
+	if count != 1 || !start.Equal(t1) {
+		t.Errorf("IncrementWindow() after the window = (%d, %v), want (1, %v)", count, start, t1)
+	}
+	if c.Items() != 1 || c.Size() != counterSize {
+		t.Errorf("items, size = %d, %d, want 1, %d", c.Items(), c.Size(), counterSize)
+	}
+}
+
+func TestIncrementWindowConcurrentReads(t *testing.T) {
+	c := New[string, int64](0)
+	IncrementWindow(c, "k", 1, 60)
+	const increments = 1000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < increments; i++ {
+			IncrementWindow(c, "k", 1, 60)
+		}
+	}()
+	// the hit paths reading the counter concurrently with the increments, checked by the race detector
+	var last int64
+	for i := 0; i < increments; i++ {
+		v := c.GetOrSet("k", 0, counterSize, 60)
+		w, _, _ := c.ComputeIfAbsent("k", counterSize, 60, func() (int64, error) { return 0, nil })
+		if v < last || w < v {
+			t.Fatalf("counter read %d, then %d after %d, want non-decreasing", v, w, last)
+		}
+		last = w
+	}
+	wg.Wait()
+	if v, _ := c.Get("k"); v != increments+1 {
+		t.Errorf("counter = %d, want %d", v, increments+1)
+	}
+}
@@ -0,0 +1,123 @@
+package expirecache
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// cowStore is a copy-on-write Store: each Set or Delete copies the map and publishes the copy,
+// so Get is a lock-free atomic load
+type cowStore[K comparable, T any] struct {
+	m atomic.Value // map[K]*Entry[T], never modified after publishing
+}
+
+func newCowStore[K comparable, T any]() *cowStore[K, T] {
+	s := &cowStore[K, T]{}
+	s.m.Store(make(map[K]*Entry[T]))
+	return s
+}
+
+func (s *cowStore[K, T]) load() map[K]*Entry[T] {
+	return s.m.Load().(map[K]*Entry[T])
+}
+
+func (s *cowStore[K, T]) Get(k K) (*Entry[T], bool) {
+	e, ok := s.load()[k]
+	return e, ok
+}
+
+func (s *cowStore[K, T]) Set(k K, e *Entry[T]) {
+	old := s.load()
+	m := make(map[K]*Entry[T], len(old)+1)
+	for ok, oe := range old {
+		m[ok] = oe
+	}
+	m[k] = e
+	s.m.Store(m)
+}
+
+func (s *cowStore[K, T]) Delete(k K) {
+	old := s.load()
+	if _, ok := old[k]; !ok {
+		return
+	}
+	m := make(map[K]*Entry[T], len(old))
+	for ok, oe := range old {
+		if ok != k {
+			m[ok] = oe
+		}
+	}
+	s.m.Store(m)
+}
+
+// clear removes all items with a single copy
+func (s *cowStore[K, T]) clear() {
+	s.m.Store(make(map[K]*Entry[T]))
+}
+
+// NewCopyOnWrite creates a new cache with a maximum memory size for read-mostly workloads:
+// the items map is copied on write and published atomically, so Get takes no lock at all
+// and never contends with other readers or writers.
+// The tradeoff is writes: each stored or removed item (including by evictions and cleaners) copies
+// the whole map, so a write costs O(items) time and garbage. Lock-free Get doesn't feed
+// the admission filter (SetAdmission) and doesn't promote hot items (SetHotPromotion).
+func NewCopyOnWrite[K comparable, T any](maxSize uint64, opts ...Option) *Cache[K, T] {
+	s := newCowStore[K, T]()
+	ec := NewWithStore[K, T](maxSize, s, opts...)
+	ec.cow = s
+	return ec
+}
+
+// getLockFree is Get for the copy-on-write mode
+func (ec *Cache[K, T]) getLockFree(k K) (item T, ok bool) {
+	now := timeNow()
+	e, ok := ec.cow.Get(k)
+	v := (*element[T])(e)
+	if !ok || !ec.alive(k, v, now) {
+		ec.lookup(k, false)
+		return item, false
+	}
+	// not ec.touch, the hot promotion settings can't be read without a lock
+	v.touch(now)
+	ec.saved(v)
+	ec.lookup(k, true)
+	return v.data, true
+}
+
+// mutate applies a change f to the element for the key and returns the changed element.
+// Published elements are read with no lock held (by the hit paths after unlocking, and by Get
+// in the copy-on-write mode), so f changes a copy which then replaces the element.
+func (ec *Cache[K, T]) mutate(k K, v *element[T], f func(v *element[T])) *element[T] {
+	c := &element[T]{
+		hits:       atomic.LoadUint64(&v.hits),
+		lastAccess: atomic.LoadInt64(&v.lastAccess),
+		validUntil: v.validUntil,
+		hardUntil:  v.hardUntil,
+		created:    v.created,
+		data:       v.data,
+		size:       v.size,
+		sticky:     v.sticky,
+		priority:   v.priority,
+		savings:    v.savings,
+		latency:    v.latency,
+		tags:       v.tags,
+		heapIdx:    v.heapIdx,
+		keyIdx:     v.keyIdx,
+		seq:        v.seq,
+		epoch:      v.epoch,
+		interned:   v.interned,
+
+		unshrinkable: v.unshrinkable,
+	}
+	f(c)
+	ec.expiry[c.heapIdx] = c
+	ec.cache.Set(k, (*Entry[T])(c))
+	return c
+}
+
+// expiresAt sets the expiration time of the element for the key
+func (ec *Cache[K, T]) expiresAt(k K, v *element[T], t time.Time) {
+	v = ec.mutate(k, v, func(v *element[T]) { v.validUntil = t })
+	heap.Fix(&ec.expiry, v.heapIdx)
+}
@@ -0,0 +1,126 @@
+package expirecache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheCopyOnWrite(t *testing.T) {
+	c := NewCopyOnWrite[string, int64](10)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("foo", 1, 3, 10)
+	c.Set("bar", 2, 3, 60, WithPriority(PriorityLow))
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Errorf("cache.Get(foo) = (%d, %v), want (1, true)", v, ok)
+	}
+	if _, ok := c.Get("missing"); ok {
+		t.Errorf("cache.Get(missing) should miss")
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("stats = %+v, want 1 hit, 1 miss", st)
+	}
+	if info, _ := c.Inspect("foo"); info.Hits != 1 {
+		t.Errorf("cache.Inspect(foo) hits = %d, want 1", info.Hits)
+	}
+
+	// in-place changes are published
+	if !c.RefreshIfBelow("foo", time.Minute, 2*time.Minute) {
+		t.Errorf("cache.RefreshIfBelow(foo) = false, want true")
+	}
+	if n, _ := IncrementWindow(c, "foo", 2, 10); n != 3 {
+		t.Errorf("IncrementWindow(foo) = %d, want 3", n)
+	}
+	timeNow = func() time.Time { return t0.Add(time.Minute + time.Second) }
+	if v, ok := c.Get("foo"); !ok || v != 3 {
+		t.Errorf("cache.Get(foo) after the refresh = (%d, %v), want (3, true)", v, ok)
+	}
+	if _, ok := c.Get("bar"); ok {
+		t.Errorf("cache.Get(bar) should miss after the expiration")
+	}
+	if info, _ := c.Inspect("foo"); info.Hits != 2 {
+		t.Errorf("cache.Inspect(foo) hits = %d after the copy, want 2", info.Hits)
+	}
+	if next, _ := c.NextExpiry(); !next.Equal(t0.Add(time.Minute)) {
+		t.Errorf("cache.NextExpiry() = %v, want the bar expiration %v", next, t0.Add(time.Minute))
+	}
+
+	// evicts bar
+	c.Set("baz", 3, 5, 60)
+	if _, ok := c.Inspect("bar"); ok {
+		t.Errorf("bar should be evicted")
+	}
+	c.Delete("baz")
+	if _, ok := c.Get("baz"); ok {
+		t.Errorf("cache.Get(baz) should miss after the delete")
+	}
+	c.ClearAll()
+	if _, ok := c.Get("foo"); ok || c.Items() != 0 {
+		t.Errorf("cache.Get(foo) should miss after ClearAll")
+	}
+}
+
+func TestCacheCopyOnWriteConcurrent(t *testing.T) {
+	c := NewCopyOnWrite[int, int64](0)
+	c.SetHotPromotion(2, time.Minute)
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if v, ok := c.Get(i % 10); ok && v < 0 {
+					t.Errorf("cache.Get(%d) = %d", i%10, v)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		c.Set(i%10, int64(i), 1, 60)
+		IncrementWindow(c, i%10, 1, 60)
+		c.RefreshIfBelow(i%10, time.Hour, time.Minute)
+	}
+	wg.Wait()
+}
+
+// BenchmarkCacheReadMostly compares the lock-free reads with the read lock ones for a write-rare workload
+func BenchmarkCacheReadMostly(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		c    *Cache[string, string]
+	}{
+		{"RWMutex", New[string, string](0)},
+		{"CopyOnWrite", NewCopyOnWrite[string, string](0)},
+	} {
+		c := bc.c
+		b.Run(bc.name, func(b *testing.B) {
+			keys := make([]string, 1000)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+				c.Set(keys[i], keys[i], 1, 3600)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var n int
+				for pb.Next() {
+					n++
+					k := keys[n%len(keys)]
+					if n%10000 == 0 {
+						c.Set(k, k, 1, 3600)
+					} else {
+						c.Get(k)
+					}
+				}
+			})
+		})
+	}
+}
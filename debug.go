@@ -0,0 +1,133 @@
+package expirecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// debugDefaultLimit is the default page size of the DebugHandler key listing
+const debugDefaultLimit = 100
+
+type debugStats struct {
+	Items     int    `json:"items"`
+	Size      uint64 `json:"size"`
+	MaxSize   uint64 `json:"max_size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type debugEntry struct {
+	Key     string `json:"key"`
+	Size    uint64 `json:"size"`
+	Expires int64  `json:"expires"` // Unix timestamp
+	Hits    uint64 `json:"hits"`
+	Value   any    `json:"value,omitempty"`
+}
+
+type debugResponse struct {
+	Stats debugStats   `json:"stats"`
+	Total int          `json:"total,omitempty"` // unexpired items in the listing
+	Keys  []debugEntry `json:"keys,omitempty"`
+}
+
+// DebugHandler returns an http.Handler serving the cache stats as JSON, for mounting into an admin mux.
+// Keys are converted with keyString (fmt.Sprint if nil). Query parameters:
+//
+//	keys=1           include the unexpired keys listing, sorted by the key string
+//	offset=N&limit=N page the keys listing (limit is 100 by default)
+//	values=1         include values in the keys listing, T must be JSON-encodable
+//	time=ms          expiration times as Unix timestamps in milliseconds (in seconds by default)
+func (ec *Cache[K, T]) DebugHandler(keyString func(k K) string) http.Handler {
+	if keyString == nil {
+		keyString = func(k K) string { return fmt.Sprint(k) }
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		offset, err := debugIntParam(q.Get("offset"), 0)
+		if err != nil {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		limit, err := debugIntParam(q.Get("limit"), debugDefaultLimit)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		var millis bool
+		switch q.Get("time") {
+		case "", "s":
+		case "ms":
+			millis = true
+		default:
+			http.Error(w, "invalid time", http.StatusBadRequest)
+			return
+		}
+
+		st := ec.Stats()
+		ec.RLock()
+		resp := debugResponse{
+			Stats: debugStats{
+				Items:     len(ec.keys),
+				Size:      ec.totalSize,
+				MaxSize:   ec.maxSize,
+				Hits:      st.Hits,
+				Misses:    st.Misses,
+				Evictions: st.Evictions,
+			},
+		}
+		ec.RUnlock()
+
+		if q.Get("keys") == "1" {
+			withValues := q.Get("values") == "1"
+			entries := ec.Entries()
+			keys := make([]debugEntry, len(entries))
+			for i := range entries {
+				keys[i] = debugEntry{
+					Key:     keyString(entries[i].Key),
+					Size:    entries[i].Size,
+					Expires: entries[i].Expires.Unix(),
+					Hits:    entries[i].Hits,
+				}
+				if millis {
+					keys[i].Expires = entries[i].Expires.UnixNano() / 1e6
+				}
+				if withValues {
+					keys[i].Value = entries[i].Value
+				}
+			}
+			sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+			resp.Total = len(keys)
+			if offset > len(keys) {
+				offset = len(keys)
+			}
+			keys = keys[offset:]
+			if limit < len(keys) {
+				keys = keys[:limit]
+			}
+			resp.Keys = keys
+		}
+
+		body, err := json.Marshal(&resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+func debugIntParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 0 {
+		err = strconv.ErrRange
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+package expirecache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCacheDebugHandler(t *testing.T) {
+	c := New[int, string](1000)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Unix(1700000000, 123456789)
+	timeNow = func() time.Time { return t0 }
+
+	for i := 0; i < 5; i++ {
+		c.Set(i, "value"+strconv.Itoa(i), uint64(i+1), 60)
+	}
+	c.Get(1)
+	c.Get(10)
+
+	srv := httptest.NewServer(c.DebugHandler(func(k int) string { return "key" + strconv.Itoa(k) }))
+	defer srv.Close()
+
+	get := func(query string) (resp debugResponse) {
+		t.Helper()
+		r, err := http.Get(srv.URL + "?" + query)
+		if err != nil {
+			t.Fatalf("GET ?%s error = %v", query, err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("GET ?%s status = %d", query, r.StatusCode)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET ?%s Content-Type = %q", query, ct)
+		}
+		if err = json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Fatalf("GET ?%s decode error = %v", query, err)
+		}
+		return resp
+	}
+
+	wantStats := debugStats{Items: 5, Size: 15, MaxSize: 1000, Hits: 1, Misses: 1}
+	resp := get("")
+	if resp.Stats != wantStats || resp.Keys != nil {
+		t.Errorf("stats response = %+v, want %+v without keys", resp, wantStats)
+	}
+
+	resp = get("keys=1&offset=1&limit=2")
+	want := debugResponse{
+		Stats: wantStats,
+		Total: 5,
+		Keys: []debugEntry{
+			{Key: "key1", Size: 2, Expires: 1700000060, Hits: 1},
+			{Key: "key2", Size: 3, Expires: 1700000060},
+		},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("keys response = %+v, want %+v", resp, want)
+	}
+
+	resp = get("keys=1&values=1&offset=4&time=ms")
+	want.Keys = []debugEntry{{Key: "key4", Size: 5, Expires: 1700000060123, Value: "value4"}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("values response = %+v, want %+v", resp, want)
+	}
+
+	for _, query := range []string{"keys=1&limit=-1", "keys=1&time=ns"} {
+		r, err := http.Get(srv.URL + "?" + query)
+		if err != nil {
+			t.Fatalf("GET ?%s error = %v", query, err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET ?%s status = %d, want %d", query, r.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
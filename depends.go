@@ -0,0 +1,74 @@
+package expirecache
+
+// DependsOn declares the keys the item is derived from, so Set or Delete of any of them
+// (transitively) invalidates the item. The option is for the items of this cache
+// (or of another cache with the same key type).
+func (ec *Cache[K, T]) DependsOn(keys ...K) SetOption {
+	return func(o *setOptions) {
+		o.deps = keys
+	}
+}
+
+// linkDeps records the item k depends on deps
+func (ec *Cache[K, T]) linkDeps(k K, deps []K) {
+	if len(deps) == 0 {
+		return
+	}
+	if ec.deps == nil {
+		ec.deps = make(map[K][]K)
+		ec.dependents = make(map[K]map[K]struct{})
+	}
+	ec.deps[k] = deps
+	for _, d := range deps {
+		dependents, ok := ec.dependents[d]
+		if !ok {
+			dependents = make(map[K]struct{})
+			ec.dependents[d] = dependents
+		}
+		dependents[k] = struct{}{}
+	}
+}
+
+// unlinkDeps removes the item k dependencies from the graph
+func (ec *Cache[K, T]) unlinkDeps(k K) {
+	deps, ok := ec.deps[k]
+	if !ok {
+		return
+	}
+	delete(ec.deps, k)
+	for _, d := range deps {
+		dependents := ec.dependents[d]
+		delete(dependents, k)
+		if len(dependents) == 0 {
+			delete(ec.dependents, d)
+		}
+	}
+}
+
+// invalidateDependents removes all items depending (transitively) on k, but not k itself.
+// Each key is visited once, so dependency cycles are safe.
+func (ec *Cache[K, T]) invalidateDependents(k K) {
+	if len(ec.dependents[k]) == 0 {
+		return
+	}
+	visited := map[K]struct{}{k: {}}
+	queue := []K{k}
+	for len(queue) > 0 {
+		dk := queue[0]
+		queue = queue[1:]
+		for d := range ec.dependents[dk] {
+			if _, ok := visited[d]; !ok {
+				visited[d] = struct{}{}
+				queue = append(queue, d)
+			}
+		}
+		if dk == k {
+			continue
+		}
+		if v, ok := ec.get(dk); ok {
+			ec.removeAt(v.keyIdx)
+		} else {
+			ec.unlinkDeps(dk)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package expirecache
+
+import (
+	"testing"
+)
+
+func TestCacheDependsOn(t *testing.T) {
+	c := New[string, string](0)
+
+	// root <- derived <- derived2, other independent
+	c.Set("root", "r", 1, 60)
+	c.Set("derived", "d", 1, 60, c.DependsOn("root"))
+	c.Set("derived2", "d2", 1, 60, c.DependsOn("derived"))
+	c.Set("other", "o", 1, 60)
+
+	checkPresent := func(name string, want map[string]bool) {
+		t.Helper()
+		for k, w := range want {
+			if _, ok := c.Get(k); ok != w {
+				t.Errorf("%s: cache.Get(%s) = %v, want %v", name, k, ok, w)
+			}
+		}
+	}
+
+	// overwrite of the root cascades
+	c.Set("root", "r2", 1, 60)
+	checkPresent("overwrite root", map[string]bool{"root": true, "derived": false, "derived2": false, "other": true})
+	if c.Items() != 2 || c.Size() != 2 {
+		t.Errorf("items, size = %d, %d, want 2, 2", c.Items(), c.Size())
+	}
+
+	// delete of the middle node cascades only to the dependents
+	c.Set("derived", "d", 1, 60, c.DependsOn("root"))
+	c.Set("derived2", "d2", 1, 60, c.DependsOn("derived", "other"))
+	c.Delete("derived")
+	checkPresent("delete derived", map[string]bool{"root": true, "derived": false, "derived2": false, "other": true})
+
+	// self dependency
+	c.Set("self", "s", 1, 60, c.DependsOn("self"))
+	c.Set("self", "s", 1, 60, c.DependsOn("self"))
+	checkPresent("self dependency", map[string]bool{"self": true})
+	c.Delete("self")
+	checkPresent("delete self dependency", map[string]bool{"self": false})
+
+	// cycle (can't be built with Set, each Set invalidates the dependents): a <-> b, c depends on b
+	c.Set("a", "a", 1, 60)
+	c.Set("b", "b", 1, 60, c.DependsOn("a"))
+	c.dependents["b"] = map[string]struct{}{"a": {}}
+	c.deps["a"] = []string{"b"}
+	c.Set("c", "c", 1, 60, c.DependsOn("b"))
+	if !c.Delete("a") {
+		t.Errorf("Delete(a) should be done")
+	}
+	checkPresent("delete cycle", map[string]bool{"a": false, "b": false, "c": false, "root": true, "other": true})
+
+	// dependents of the removed items are dropped from the graph
+	c.Delete("root")
+	c.Delete("other")
+	if len(c.deps) != 0 || len(c.dependents) != 0 {
+		t.Errorf("dependency graph should be empty, got deps %v, dependents %v", c.deps, c.dependents)
+	}
+	if c.Items() != 0 || c.Size() != 0 {
+		t.Errorf("items, size = %d, %d, want 0, 0", c.Items(), c.Size())
+	}
+}
+
+func TestCacheDependsOnKeyType(t *testing.T) {
+	// the untyped constant keys are of the cache key type
+	c := New[int64, string](0)
+	c.Set(1, "a", 1, 60)
+	c.Set(2, "b", 1, 60, c.DependsOn(1))
+	c.Delete(1)
+	if _, ok := c.Get(2); ok {
+		t.Errorf("cache.Get(2) after the dependency deleted should miss")
+	}
+}
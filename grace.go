@@ -0,0 +1,45 @@
+package expirecache
+
+import "time"
+
+// SetGracePeriod sets the grace period after the items expiration: Get misses an expired item,
+// but GetGrace still serves it until the grace period ends (e.g. while the caller refreshes it),
+// and the cleaners remove it only after that.
+func (ec *Cache[K, T]) SetGracePeriod(d time.Duration) {
+	ec.Lock()
+	ec.grace = d
+	ec.Unlock()
+}
+
+// GetGrace returns the item from the cache like Get, or the expired item within the grace period
+// (see SetGracePeriod) with grace set to true. Items past the grace period (or invalidated by BumpNamespace) are a miss.
+func (ec *Cache[K, T]) GetGrace(k K) (item T, grace, ok bool) {
+	now := timeNow()
+	ec.RLock()
+	v, ok := ec.get(k)
+	if !ok || ec.pastGrace(v, now) || ec.stale(k, v) {
+		ec.RUnlock()
+		ec.lookup(k, false)
+		return item, false, false
+	}
+	item = v.data
+	grace = v.validUntil.Before(now)
+	// an expired item isn't promoted, it would be brought back to life
+	promote := ec.touch(v, now) && !grace
+	ec.RUnlock()
+	if promote {
+		ec.promote(k, v)
+	}
+	ec.lookup(k, true)
+	return item, grace, true
+}
+
+// pastGrace checks if the element is expired (by the expiration time before MarkStale, if later)
+// and past the grace period, so it can be removed by the cleaners
+func (ec *Cache[K, T]) pastGrace(v *element[T], now time.Time) bool {
+	until := v.validUntil
+	if v.hardUntil.After(until) {
+		until = v.hardUntil
+	}
+	return until.Add(ec.grace).Before(now)
+}
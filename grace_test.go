@@ -0,0 +1,51 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheGracePeriod(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.SetGracePeriod(10 * time.Second)
+	c.Set("foo", "bar", 3, 5)
+
+	check := func(name string, wantGrace, wantOK bool) {
+		t.Helper()
+		v, grace, ok := c.GetGrace("foo")
+		if grace != wantGrace || ok != wantOK || (ok && v != "bar") {
+			t.Errorf("%s: cache.GetGrace(foo) = (%q, %v, %v), want (%q, %v, %v)", name, v, grace, ok, "bar", wantGrace, wantOK)
+		}
+	}
+
+	check("fresh", false, true)
+
+	// within the grace period
+	timeNow = func() time.Time { return t0.Add(10 * time.Second) }
+	check("grace", true, true)
+	if _, ok := c.Get("foo"); ok {
+		t.Errorf("cache.Get(foo) of the expired item should miss")
+	}
+	c.cleanAll(timeNow())
+	c.clean(timeNow())
+	if c.Items() != 1 {
+		t.Errorf("items = %d after the cleanup within the grace period, want 1", c.Items())
+	}
+	check("grace after the cleanup", true, true)
+
+	// past the grace period
+	timeNow = func() time.Time { return t0.Add(16 * time.Second) }
+	check("past grace", false, false)
+	c.cleanAll(timeNow())
+	if c.Items() != 0 {
+		t.Errorf("items = %d after the cleanup past the grace period, want 0", c.Items())
+	}
+}
@@ -0,0 +1,107 @@
+package expirecache
+
+import (
+	"sync"
+	"time"
+)
+
+// WindowStats contains the cache counters deltas over a time window, see StatsHistory
+type WindowStats struct {
+	Start time.Time
+	End   time.Time
+	Stats
+}
+
+// HitRate returns the hit rate over the window, 0 if there were no lookups
+func (w WindowStats) HitRate() float64 {
+	lookups := w.Hits + w.Misses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(w.Hits) / float64(lookups)
+}
+
+// statsHistory is a ring buffer of the last stats windows
+type statsHistory struct {
+	mu      sync.Mutex
+	windows []WindowStats
+	pos     int
+	last    Stats
+	lastAt  time.Time
+}
+
+// StatsHistorian starts a goroutine which records the cache counters deltas every d, retaining the last windows
+// of them for StatsHistory, until exit is closed. The deltas of a window reset by StatsAndReset are counted
+// from the reset.
+func (ec *Cache[K, T]) StatsHistorian(d time.Duration, windows int, exit <-chan struct{}) {
+	if windows <= 0 {
+		return
+	}
+	h := &statsHistory{windows: make([]WindowStats, 0, windows), last: ec.Stats(), lastAt: timeNow()}
+	ec.Lock()
+	ec.history = h
+	ec.Unlock()
+	for {
+		select {
+		case <-exit:
+			return
+		default:
+		}
+
+		statsWindowSleep(d)
+
+		h.record(ec.Stats(), timeNow())
+
+		statsWindowDone()
+	}
+}
+
+// StatsHistory returns the windows recorded by StatsHistorian, the oldest first. It's nil if no historian was started.
+func (ec *Cache[K, T]) StatsHistory() []WindowStats {
+	ec.RLock()
+	h := ec.history
+	ec.RUnlock()
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	windows := make([]WindowStats, 0, len(h.windows))
+	windows = append(windows, h.windows[h.pos:]...)
+	windows = append(windows, h.windows[:h.pos]...)
+	h.mu.Unlock()
+	return windows
+}
+
+func (h *statsHistory) record(st Stats, now time.Time) {
+	w := WindowStats{
+		Start: h.lastAt,
+		End:   now,
+		Stats: Stats{
+			Hits:         delta(st.Hits, h.last.Hits),
+			Misses:       delta(st.Misses, h.last.Misses),
+			Evictions:    delta(st.Evictions, h.last.Evictions),
+			WorkSaved:    delta(st.WorkSaved, h.last.WorkSaved),
+			Reclaimed:    delta(st.Reclaimed, h.last.Reclaimed),
+			RateLimited:  delta(st.RateLimited, h.last.RateLimited),
+			LatencySaved: time.Duration(delta(uint64(st.LatencySaved), uint64(h.last.LatencySaved))),
+		},
+	}
+	h.mu.Lock()
+	if len(h.windows) < cap(h.windows) {
+		h.windows = append(h.windows, w)
+	} else {
+		h.windows[h.pos] = w
+		h.pos = (h.pos + 1) % len(h.windows)
+	}
+	h.mu.Unlock()
+	h.last = st
+	h.lastAt = now
+}
+
+// delta returns the counter increase since last, or the counter itself if it was reset in the meantime
+func delta(counter, last uint64) uint64 {
+	if counter < last {
+		return counter
+	}
+	return counter - last
+}
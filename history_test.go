@@ -0,0 +1,67 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheStatsHistory(t *testing.T) {
+	c := New[string, string](0)
+	if got := c.StatsHistory(); got != nil {
+		t.Errorf("cache.StatsHistory() without a historian = %v, want nil", got)
+	}
+
+	sleep := make(chan bool)
+	statsWindowSleep = mockCleanerSleep(sleep)
+	done := make(chan bool)
+	statsWindowDone = func() { <-done }
+
+	defer func() {
+		stopCleaner(sleep)
+		statsWindowSleep = time.Sleep
+		statsWindowDone = func() {}
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+	c.Set("foo", "bar", 1, 3600)
+	c.Get("foo") // before the historian, not counted
+
+	go c.StatsHistorian(time.Minute, 3, make(chan struct{}))
+
+	// an empty window once the historian is started
+	sleep <- true
+	done <- true
+	// window i has i hits and a miss
+	for i := 1; i <= 5; i++ {
+		for j := 0; j < i; j++ {
+			c.Get("foo")
+		}
+		c.Get("bar")
+		at := t0.Add(time.Duration(i) * time.Minute)
+		timeNow = func() time.Time { return at }
+		sleep <- true
+		done <- true
+	}
+
+	windows := c.StatsHistory()
+	if len(windows) != 3 {
+		t.Fatalf("cache.StatsHistory() windows = %d, want 3", len(windows))
+	}
+	for i, w := range windows {
+		want := uint64(i + 3)
+		if w.Hits != want || w.Misses != 1 {
+			t.Errorf("window %d hits, misses = %d, %d, want %d, 1", i, w.Hits, w.Misses, want)
+		}
+		if want := float64(want) / float64(want+1); w.HitRate() != want {
+			t.Errorf("window %d hit rate = %v, want %v", i, w.HitRate(), want)
+		}
+		if start := t0.Add(time.Duration(i+2) * time.Minute); !w.Start.Equal(start) || !w.End.Equal(start.Add(time.Minute)) {
+			t.Errorf("window %d = [%v, %v], want a minute from %v", i, w.Start, w.End, start)
+		}
+	}
+	if cfg := c.Config(); cfg.StatsWindows != 3 {
+		t.Errorf("cache.Config().StatsWindows = %d, want 3", cfg.StatsWindows)
+	}
+}
@@ -0,0 +1,52 @@
+package expirecache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// hitRateTracker computes the hit rate over windows of lookups
+type hitRateTracker struct {
+	// updated atomically, keep first for 64-bit alignment
+	lookups   uint64
+	hits      uint64
+	lastFired int64 // unix nanoseconds
+
+	threshold float64
+	window    uint64
+	interval  time.Duration
+	f         func(rate float64)
+}
+
+// SetOnLowHitRate sets a callback invoked when the hit rate over the last window lookups (Get, GetOrSet)
+// falls below threshold. The callback is invoked at most once per interval, with no lock held,
+// in the goroutine doing the lookup which completes the window. Pass a nil f to disable.
+func (ec *Cache[K, T]) SetOnLowHitRate(threshold float64, window uint64, interval time.Duration, f func(rate float64)) {
+	if f == nil || window == 0 {
+		ec.hitRate.Store((*hitRateTracker)(nil))
+		return
+	}
+	ec.hitRate.Store(&hitRateTracker{threshold: threshold, window: window, interval: interval, f: f})
+}
+
+func (hr *hitRateTracker) record(hit bool) {
+	if hit {
+		atomic.AddUint64(&hr.hits, 1)
+	}
+	if atomic.AddUint64(&hr.lookups, 1)%hr.window != 0 {
+		return
+	}
+	// concurrent lookups may be counted in the next window, it's only an estimation
+	rate := float64(atomic.SwapUint64(&hr.hits, 0)) / float64(hr.window)
+	if rate >= hr.threshold {
+		return
+	}
+	now := timeNow().UnixNano()
+	last := atomic.LoadInt64(&hr.lastFired)
+	if last != 0 && now-last < int64(hr.interval) {
+		return
+	}
+	if atomic.CompareAndSwapInt64(&hr.lastFired, last, now) {
+		hr.f(rate)
+	}
+}
@@ -0,0 +1,64 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheOnLowHitRate(t *testing.T) {
+	c := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	var rates []float64
+	c.SetOnLowHitRate(0.5, 100, time.Minute, func(rate float64) {
+		rates = append(rates, rate)
+	})
+
+	c.Set(0, 0, 1, 3600)
+
+	// mostly hits
+	for i := 0; i < 100; i++ {
+		c.Get(i % 2)
+	}
+	if len(rates) != 0 {
+		t.Fatalf("OnLowHitRate should not be called for hit rate 0.5, got %v", rates)
+	}
+
+	// mostly misses, 10% hits
+	for i := 0; i < 100; i++ {
+		c.Get(i % 10)
+	}
+	if len(rates) != 1 || rates[0] != 0.1 {
+		t.Fatalf("OnLowHitRate rates = %v, want [0.1]", rates)
+	}
+
+	// rate-limited
+	for i := 0; i < 100; i++ {
+		c.Get(-1)
+	}
+	if len(rates) != 1 {
+		t.Fatalf("OnLowHitRate should be rate-limited, got %v", rates)
+	}
+
+	timeNow = func() time.Time { return t0.Add(2 * time.Minute) }
+	for i := 0; i < 100; i++ {
+		c.Get(-1)
+	}
+	if len(rates) != 2 || rates[1] != 0 {
+		t.Fatalf("OnLowHitRate rates = %v, want [0.1 0]", rates)
+	}
+
+	c.SetOnLowHitRate(0, 0, 0, nil)
+	for i := 0; i < 100; i++ {
+		c.Get(-1)
+	}
+	if len(rates) != 2 {
+		t.Errorf("OnLowHitRate should be disabled, got %v", rates)
+	}
+}
@@ -0,0 +1,59 @@
+package expirecache
+
+// internedValue is a stored copy of a value shared by the items with identical values
+type internedValue[T any] struct {
+	id   string
+	data T
+	size uint64 // accounted once for all references
+	refs int
+}
+
+// SetValueInterning enables interning of identical values: items stored with a value of the same identity (e.g. the value
+// itself for strings, or a content hash) share a single stored copy, and its size is accounted once while referenced.
+// The copy is shared as is, so reference values (slices, maps, pointers) must not be modified after Set.
+// Interned items aren't shrunk (see SetShrinkFunc). id is invoked under the write lock, so it must not call back
+// into the cache. Pass nil to disable the interning of the items stored later.
+func (ec *Cache[K, T]) SetValueInterning(id func(v T) string) {
+	ec.Lock()
+	ec.internID = id
+	ec.Unlock()
+}
+
+// intern links the new element to the interned copy of its value and returns the size to account for it:
+// its size, or 0 if the copy is already referenced
+func (ec *Cache[K, T]) intern(e *element[T]) uint64 {
+	if ec.internID == nil {
+		return e.size
+	}
+	id := ec.internID(e.data)
+	iv, ok := ec.interned[id]
+	if !ok {
+		if ec.interned == nil {
+			ec.interned = make(map[string]*internedValue[T])
+		}
+		iv = &internedValue[T]{id: id, data: e.data, size: e.size}
+		ec.interned[id] = iv
+	}
+	iv.refs++
+	e.data = iv.data
+	e.interned = iv
+	if iv.refs > 1 {
+		return 0
+	}
+	return iv.size
+}
+
+// release unlinks the removed element from the interned copy of its value and returns the accounted size to free:
+// its size, or 0 if the copy is still referenced
+func (ec *Cache[K, T]) release(e *element[T]) uint64 {
+	iv := e.interned
+	if iv == nil {
+		return e.size
+	}
+	iv.refs--
+	if iv.refs > 0 {
+		return 0
+	}
+	delete(ec.interned, iv.id)
+	return iv.size
+}
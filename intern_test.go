@@ -0,0 +1,69 @@
+package expirecache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCacheValueInterning(t *testing.T) {
+	c := New[string, []byte](0)
+	c.SetValueInterning(func(v []byte) string { return string(v) })
+
+	const keys = 100
+	large := strings.Repeat("x", 1<<16)
+	for i := 0; i < keys; i++ {
+		// a distinct copy per key
+		c.Set(fmt.Sprintf("key%d", i), []byte(large), uint64(len(large)), 60)
+	}
+	c.Set("small", []byte("y"), 1, 60)
+
+	if c.Items() != keys+1 || c.Size() != uint64(len(large))+1 {
+		t.Fatalf("items = %d, size %d, want %d, %d", c.Items(), c.Size(), keys+1, len(large)+1)
+	}
+	first, _ := c.Get("key0")
+	for i := 1; i < keys; i++ {
+		k := fmt.Sprintf("key%d", i)
+		v, ok := c.Get(k)
+		if !ok || string(v) != large {
+			t.Fatalf("cache.Get(%s) = %d bytes, %v, want the large value", k, len(v), ok)
+		}
+		if &v[0] != &first[0] {
+			t.Errorf("cache.Get(%s) is a distinct copy, want the shared one", k)
+		}
+	}
+	if info, _ := c.Inspect("key1"); info.Size != uint64(len(large)) {
+		t.Errorf("cache.Inspect(key1) size = %d, want %d", info.Size, len(large))
+	}
+
+	// the shared copy is accounted until the last reference is removed
+	for i := 0; i < keys-1; i++ {
+		c.Delete(fmt.Sprintf("key%d", i))
+	}
+	if c.Size() != uint64(len(large))+1 || c.Stats().Reclaimed != 0 {
+		t.Errorf("size with a reference = %d, reclaimed %d, want %d, 0", c.Size(), c.Stats().Reclaimed, len(large)+1)
+	}
+	// overwritten with another value
+	c.Set(fmt.Sprintf("key%d", keys-1), []byte("y"), 1, 60)
+	if c.Size() != 1 || len(c.interned) != 1 {
+		t.Errorf("size = %d, interned %d, want 1, 1", c.Size(), len(c.interned))
+	}
+}
+
+func TestCacheValueInterningEviction(t *testing.T) {
+	c := New[int, string](10)
+	c.SetValueInterning(func(v string) string { return v })
+
+	for i := 0; i < 20; i++ {
+		c.Set(i, "shared", 5, 60)
+	}
+	// the shared value fits with any number of references
+	if c.Items() != 20 || c.Size() != 5 || c.Stats().Evictions != 0 {
+		t.Errorf("items = %d, size %d, evictions %d, want 20, 5, 0", c.Items(), c.Size(), c.Stats().Evictions)
+	}
+	c.Set(100, "other", 6, 60, WithPriority(PriorityHigh))
+	// all references of the shared value are evicted to free its size
+	if c.Items() != 1 || c.Size() != 6 {
+		t.Errorf("items = %d, size %d, want 1, 6", c.Items(), c.Size())
+	}
+}
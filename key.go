@@ -0,0 +1,33 @@
+package expirecache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Key builds a composite key for a Cache[string, T] from the parts.
+// Each part is encoded with its type and a length prefix, so distinct part tuples never collide:
+// Key("1", "23") != Key("12", "3") and Key(1) != Key("1") != Key(int64(1)).
+// Parts other than strings are formatted with fmt.Sprint, so they should print distinct values differently.
+func Key(parts ...any) string {
+	var b strings.Builder
+	for _, p := range parts {
+		var s string
+		if str, ok := p.(string); ok {
+			s = str
+		} else {
+			s = fmt.Sprint(p)
+		}
+		writeKeyPart(&b, fmt.Sprintf("%T", p))
+		writeKeyPart(&b, s)
+	}
+	return b.String()
+}
+
+// writeKeyPart writes s with its length prefix
+func writeKeyPart(b *strings.Builder, s string) {
+	b.WriteString(strconv.Itoa(len(s)))
+	b.WriteByte(':')
+	b.WriteString(s)
+}
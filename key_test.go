@@ -0,0 +1,47 @@
+package expirecache
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tuples := [][]any{
+		{},
+		{""},
+		{"", ""},
+		{"1", "23"},
+		{"12", "3"},
+		{"123"},
+		{1, 23},
+		{12, 3},
+		{123},
+		{"1", 23},
+		{1, "23"},
+		{int64(123)},
+		{uint(123)},
+		{"3:int"},
+		{"3:int", ""},
+		{true},
+		{"true"},
+		{nil},
+		{"<nil>"},
+		{1.5},
+	}
+
+	seen := make(map[string]int)
+	for i, parts := range tuples {
+		k := Key(parts...)
+		if j, ok := seen[k]; ok {
+			t.Errorf("Key(%#v) = Key(%#v) = %q", parts, tuples[j], k)
+		}
+		seen[k] = i
+
+		if k2 := Key(parts...); k2 != k {
+			t.Errorf("Key(%#v) isn't deterministic: %q, %q", parts, k, k2)
+		}
+	}
+
+	c := New[string, string](0)
+	c.Set(Key("user", 42), "foo", 3, 10)
+	if v, ok := c.Get(Key("user", 42)); !ok || v != "foo" {
+		t.Errorf("cache.Get(Key(user, 42)) = (%q, %v), want (%q, true)", v, ok, "foo")
+	}
+}
@@ -0,0 +1,58 @@
+package expirecache
+
+import "sync"
+
+// keyLocks is a set of per-key mutexes, allocated while they are used
+type keyLocks[K comparable] struct {
+	mu sync.Mutex
+	m  map[K]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int // lockers holding or waiting for the mutex
+}
+
+type keyLocker[K comparable] struct {
+	locks *keyLocks[K]
+	k     K
+}
+
+// KeyLock returns a lock for the key, for serializing external operations tied to the cache key.
+// Locks for the same key exclude each other, locks for different keys are independent.
+// It isn't related to the cache own locking, so the cache may be used while holding it.
+func (ec *Cache[K, T]) KeyLock(k K) sync.Locker {
+	return keyLocker[K]{locks: &ec.keyLocks, k: k}
+}
+
+func (l keyLocker[K]) Lock() {
+	l.locks.mu.Lock()
+	if l.locks.m == nil {
+		l.locks.m = make(map[K]*keyLock)
+	}
+	kl, ok := l.locks.m[l.k]
+	if !ok {
+		kl = &keyLock{}
+		l.locks.m[l.k] = kl
+	}
+	kl.refs++
+	l.locks.mu.Unlock()
+
+	kl.Lock()
+}
+
+func (l keyLocker[K]) Unlock() {
+	l.locks.mu.Lock()
+	kl, ok := l.locks.m[l.k]
+	if !ok {
+		l.locks.mu.Unlock()
+		panic("expirecache: unlock of unlocked key lock")
+	}
+	kl.refs--
+	if kl.refs == 0 {
+		delete(l.locks.m, l.k)
+	}
+	l.locks.mu.Unlock()
+
+	kl.Unlock()
+}
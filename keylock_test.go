@@ -0,0 +1,73 @@
+package expirecache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyLock(t *testing.T) {
+	c := New[string, int](0)
+
+	// different keys proceed concurrently
+	foo := c.KeyLock("foo")
+	foo.Lock()
+	locked := make(chan struct{})
+	go func() {
+		bar := c.KeyLock("bar")
+		bar.Lock()
+		bar.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("KeyLock(bar) should not be blocked by KeyLock(foo)")
+	}
+
+	// same key serializes
+	locked = make(chan struct{})
+	go func() {
+		l := c.KeyLock("foo")
+		l.Lock()
+		close(locked)
+		l.Unlock()
+	}()
+	select {
+	case <-locked:
+		t.Fatalf("KeyLock(foo) should be blocked by the held KeyLock(foo)")
+	case <-time.After(50 * time.Millisecond):
+	}
+	foo.Unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("KeyLock(foo) should be acquired after unlock")
+	}
+
+	// read-modify-write under the key lock
+	const workers = 16
+	c.Set("counter", 0, 1, 60)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				l := c.KeyLock("counter")
+				l.Lock()
+				v, _ := c.Get("counter")
+				c.Set("counter", v+1, 1, 60)
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := c.Get("counter"); v != workers*100 {
+		t.Errorf("counter = %d, want %d", v, workers*100)
+	}
+
+	if len(c.keyLocks.m) != 0 {
+		t.Errorf("unused key locks should be released, got %d", len(c.keyLocks.m))
+	}
+}
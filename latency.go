@@ -0,0 +1,47 @@
+package expirecache
+
+import "time"
+
+// Op is a cache operation with the latency recorded by a LatencyRecorder
+type Op int
+
+const (
+	OpGet Op = iota
+	OpSet
+)
+
+// String returns the operation name
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	default:
+		return "unknown"
+	}
+}
+
+// LatencyRecorder records the operations latency, e.g. to a histogram
+type LatencyRecorder interface {
+	Record(op Op, d time.Duration)
+}
+
+// latencyRecorder holds a LatencyRecorder, so any implementation can be stored in atomic.Value
+type latencyRecorder struct {
+	r LatencyRecorder
+}
+
+// SetLatencyRecorder sets a recorder of the Get and Set latency, including the lock wait.
+// It's called with no lock held, in the goroutine doing the operation. Pass nil to disable,
+// disabled recording costs a single atomic load per operation.
+func (ec *Cache[K, T]) SetLatencyRecorder(r LatencyRecorder) {
+	ec.latency.Store(&latencyRecorder{r: r})
+}
+
+func (ec *Cache[K, T]) latencyRecorder() LatencyRecorder {
+	if lr, _ := ec.latency.Load().(*latencyRecorder); lr != nil {
+		return lr.r
+	}
+	return nil
+}
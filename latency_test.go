@@ -0,0 +1,57 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeRecorder struct {
+	ops       []Op
+	durations []time.Duration
+}
+
+func (r *fakeRecorder) Record(op Op, d time.Duration) {
+	r.ops = append(r.ops, op)
+	r.durations = append(r.durations, d)
+}
+
+func TestCacheLatencyRecorder(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	// each clock reading advances it by a millisecond
+	t0 := time.Now()
+	var ticks int
+	timeNow = func() time.Time {
+		ticks++
+		return t0.Add(time.Duration(ticks) * time.Millisecond)
+	}
+
+	// disabled
+	c.Set("foo", "bar", 3, 60)
+	c.Get("foo")
+
+	var r fakeRecorder
+	c.SetLatencyRecorder(&r)
+	c.Set("foo", "bar", 3, 60)
+	c.Get("foo")
+	c.Get("missing")
+
+	if want := []Op{OpSet, OpGet, OpGet}; len(r.ops) != len(want) || r.ops[0] != want[0] || r.ops[1] != want[1] || r.ops[2] != want[2] {
+		t.Fatalf("recorded ops = %v, want %v", r.ops, want)
+	}
+	for i, d := range r.durations {
+		if d <= 0 {
+			t.Errorf("recorded %v duration = %v, want > 0", r.ops[i], d)
+		}
+	}
+
+	c.SetLatencyRecorder(nil)
+	c.Get("foo")
+	if len(r.ops) != 3 {
+		t.Errorf("recorded ops = %v after disabling, want 3", r.ops)
+	}
+}
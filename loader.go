@@ -0,0 +1,69 @@
+package expirecache
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNoLoader is returned by GetOrLoad for a key without a matching loader
+var ErrNoLoader = errors.New("expirecache: no loader for the key")
+
+// RegisterLoader registers (or replaces) the loader for the keys with the prefix, for GetOrLoad.
+// Prefixes are matched for string keys only, a loader with the empty prefix is the default one for all keys.
+func (ec *Cache[K, T]) RegisterLoader(prefix string, load LoaderFunc[K, T]) {
+	ec.Lock()
+	if ec.loaders == nil {
+		ec.loaders = make(map[string]LoaderFunc[K, T])
+	}
+	ec.loaders[prefix] = load
+	ec.Unlock()
+}
+
+// GetOrLoad returns the unexpired item from the cache, or loads it with the loader registered for the longest
+// matching key prefix (see RegisterLoader) and stores it, like ComputeIfAbsent: concurrent misses for the key
+// wait for a single load. A load error is returned and nothing is stored, ErrNoLoader is returned if no loader matches.
+func (ec *Cache[K, T]) GetOrLoad(k K) (T, error) {
+	ec.RLock()
+	load := ec.loader(k)
+	ec.RUnlock()
+	if load == nil {
+		if item, ok := ec.Get(k); ok {
+			return item, nil
+		}
+		var zero T
+		return zero, ErrNoLoader
+	}
+	v, _, err := ec.computeIfAbsent(k, func() (T, uint64, int32, error) {
+		return load(k)
+	})
+	return v, err
+}
+
+// loader returns the loader for the longest matching key prefix, nil if none matches
+func (ec *Cache[K, T]) loader(k K) LoaderFunc[K, T] {
+	s, isString := any(k).(string)
+	if !isString {
+		return ec.loaders[""]
+	}
+	var (
+		load    LoaderFunc[K, T]
+		longest = -1
+	)
+	for prefix, l := range ec.loaders {
+		if len(prefix) > longest && strings.HasPrefix(s, prefix) {
+			load, longest = l, len(prefix)
+		}
+	}
+	return load
+}
+
+// Fetch returns the unexpired item from the cache, or loads it with load and stores it with the size and the expiration
+// time in seconds returned by load (clamped to the int32 range of Set), like ComputeIfAbsent: concurrent misses
+// for the key wait for a single load. A load error is returned and nothing is stored.
+func (ec *Cache[K, T]) Fetch(k K, load func(k K) (T, uint64, int64, error)) (T, error) {
+	v, _, err := ec.computeIfAbsent(k, func() (T, uint64, int32, error) {
+		v, size, expire, err := load(k)
+		return v, size, clampExpire(expire), err
+	})
+	return v, err
+}
@@ -0,0 +1,132 @@
+package expirecache
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c := New[string, string](0)
+
+	calls := make(map[string]int)
+	loader := func(name string) LoaderFunc[string, string] {
+		return func(k string) (string, uint64, int32, error) {
+			calls[name]++
+			if k == "user:fail" {
+				return "", 0, 0, errors.New("fail")
+			}
+			return name + " " + k, 1, 60, nil
+		}
+	}
+	c.RegisterLoader("user:", loader("users"))
+	c.RegisterLoader("user:admin:", loader("admins"))
+	c.RegisterLoader("post:", loader("posts"))
+
+	for k, want := range map[string]string{
+		"user:1":       "users user:1",
+		"user:admin:1": "admins user:admin:1",
+		"post:1":       "posts post:1",
+	} {
+		for i := 0; i < 2; i++ {
+			if v, err := c.GetOrLoad(k); err != nil || v != want {
+				t.Errorf("cache.GetOrLoad(%s) = (%q, %v), want (%q, nil)", k, v, err, want)
+			}
+		}
+	}
+	if calls["users"] != 1 || calls["admins"] != 1 || calls["posts"] != 1 {
+		t.Errorf("loader calls = %v, want one per loader", calls)
+	}
+
+	if _, err := c.GetOrLoad("user:fail"); err == nil {
+		t.Errorf("cache.GetOrLoad(user:fail) should fail")
+	}
+	if _, ok := c.Get("user:fail"); ok {
+		t.Errorf("failed load should not be stored")
+	}
+	if _, err := c.GetOrLoad("comment:1"); err != ErrNoLoader {
+		t.Errorf("cache.GetOrLoad(comment:1) error = %v, want ErrNoLoader", err)
+	}
+	// a stored item is served without a loader
+	c.Set("comment:2", "bar", 1, 60)
+	if v, err := c.GetOrLoad("comment:2"); err != nil || v != "bar" {
+		t.Errorf("cache.GetOrLoad(comment:2) = (%q, %v), want (%q, nil)", v, err, "bar")
+	}
+
+	// the default loader
+	c.RegisterLoader("", loader("default"))
+	if v, err := c.GetOrLoad("comment:1"); err != nil || v != "default comment:1" {
+		t.Errorf("cache.GetOrLoad(comment:1) = (%q, %v), want the default loader", v, err)
+	}
+}
+
+func TestCacheGetOrLoadNonString(t *testing.T) {
+	c := New[int, int](0)
+	if _, err := c.GetOrLoad(1); err != ErrNoLoader {
+		t.Errorf("cache.GetOrLoad(1) error = %v, want ErrNoLoader", err)
+	}
+	c.RegisterLoader("1", func(k int) (int, uint64, int32, error) { return -k, 1, 60, nil })
+	if _, err := c.GetOrLoad(1); err != ErrNoLoader {
+		t.Errorf("cache.GetOrLoad(1) error = %v, want ErrNoLoader for a prefix loader", err)
+	}
+	c.RegisterLoader("", func(k int) (int, uint64, int32, error) { return k * 2, 1, 60, nil })
+	if v, err := c.GetOrLoad(2); err != nil || v != 4 {
+		t.Errorf("cache.GetOrLoad(2) = (%d, %v), want (4, nil)", v, err)
+	}
+}
+
+func TestCacheFetch(t *testing.T) {
+	c := New[string, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	var calls int
+	load := func(k string) (int, uint64, int64, error) {
+		calls++
+		if k == "fail" {
+			return 0, 0, 0, errors.New("fail")
+		}
+		if k == "forever" {
+			return calls, 1, math.MaxInt64, nil
+		}
+		return calls, 2, 60, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, err := c.Fetch("foo", load); err != nil || v != 1 {
+			t.Errorf("cache.Fetch(foo) = (%d, %v), want (1, nil)", v, err)
+		}
+	}
+	if info, _ := c.Inspect("foo"); info.Size != 2 || info.TTL != time.Minute {
+		t.Errorf("cache.Inspect(foo) = %+v, want size 2 with TTL 1m", info)
+	}
+
+	// refreshed after the expiration
+	timeNow = func() time.Time { return t0.Add(61 * time.Second) }
+	if v, err := c.Fetch("foo", load); err != nil || v != 2 {
+		t.Errorf("cache.Fetch(foo) after the expiration = (%d, %v), want (2, nil)", v, err)
+	}
+
+	if _, err := c.Fetch("fail", load); err == nil {
+		t.Errorf("cache.Fetch(fail) should fail")
+	}
+	if _, ok := c.Get("fail"); ok {
+		t.Errorf("failed fetch should not be stored")
+	}
+	// the TTL is clamped instead of wrapping around
+	if _, err := c.Fetch("forever", load); err != nil {
+		t.Errorf("cache.Fetch(forever) error = %v", err)
+	}
+	if info, ok := c.Inspect("forever"); !ok || info.TTL != math.MaxInt32*time.Second {
+		t.Errorf("cache.Inspect(forever) = (%+v, %v), want TTL of MaxInt32 seconds", info, ok)
+	}
+	if calls != 4 {
+		t.Errorf("loader calls = %d, want 4", calls)
+	}
+}
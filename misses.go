@@ -0,0 +1,84 @@
+package expirecache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyMisses is a key with its miss streak, see FrequentMisses
+type KeyMisses[K comparable] struct {
+	Key      K
+	Streak   uint64 // consecutive misses since the last hit
+	LastMiss time.Time
+}
+
+// missTracker records the miss streaks of recently missed keys
+type missTracker[K comparable] struct {
+	mu     sync.Mutex
+	size   int
+	streak map[K]*KeyMisses[K]
+}
+
+// SetMissTracking enables tracking of the miss streaks of up to size recently missed keys, reported by FrequentMisses.
+// When full, the key with the shortest (then the oldest) streak is dropped for a new one. A zero size disables the tracking.
+func (ec *Cache[K, T]) SetMissTracking(size int) {
+	if size <= 0 {
+		ec.misses.Store((*missTracker[K])(nil))
+		return
+	}
+	ec.misses.Store(&missTracker[K]{size: size, streak: make(map[K]*KeyMisses[K], size)})
+}
+
+// FrequentMisses returns up to n tracked keys with the longest miss streaks (see SetMissTracking), longest first.
+// Keys missed repeatedly are candidates for a longer TTL.
+func (ec *Cache[K, T]) FrequentMisses(n int) []KeyMisses[K] {
+	mt, _ := ec.misses.Load().(*missTracker[K])
+	if mt == nil || n <= 0 {
+		return nil
+	}
+	mt.mu.Lock()
+	misses := make([]KeyMisses[K], 0, len(mt.streak))
+	for _, m := range mt.streak {
+		misses = append(misses, *m)
+	}
+	mt.mu.Unlock()
+	sort.Slice(misses, func(i, j int) bool {
+		if misses[i].Streak == misses[j].Streak {
+			return misses[i].LastMiss.After(misses[j].LastMiss)
+		}
+		return misses[i].Streak > misses[j].Streak
+	})
+	if len(misses) > n {
+		misses = misses[:n]
+	}
+	return misses
+}
+
+func (mt *missTracker[K]) record(k K, hit bool) {
+	mt.mu.Lock()
+	if hit {
+		// the streak ends
+		delete(mt.streak, k)
+		mt.mu.Unlock()
+		return
+	}
+	now := timeNow()
+	if m, ok := mt.streak[k]; ok {
+		m.Streak++
+		m.LastMiss = now
+		mt.mu.Unlock()
+		return
+	}
+	if len(mt.streak) >= mt.size {
+		var drop *KeyMisses[K]
+		for _, m := range mt.streak {
+			if drop == nil || m.Streak < drop.Streak || (m.Streak == drop.Streak && m.LastMiss.Before(drop.LastMiss)) {
+				drop = m
+			}
+		}
+		delete(mt.streak, drop.Key)
+	}
+	mt.streak[k] = &KeyMisses[K]{Key: k, Streak: 1, LastMiss: now}
+	mt.mu.Unlock()
+}
@@ -0,0 +1,53 @@
+package expirecache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheFrequentMisses(t *testing.T) {
+	c := New[string, string](0)
+	if got := c.FrequentMisses(10); got != nil {
+		t.Errorf("cache.FrequentMisses() without tracking = %v, want nil", got)
+	}
+	c.SetMissTracking(3)
+
+	for i := 0; i < 5; i++ {
+		c.Get("foo")
+	}
+	c.Get("bar")
+	c.Get("bar")
+	c.GetOrSet("baz", "v", 1, -1) // stored expired
+	c.Get("baz")
+	c.Get("baz")
+	c.Set("qux", "v", 1, 60)
+	c.Get("qux")
+
+	keys := func(misses []KeyMisses[string]) []string {
+		var keys []string
+		for _, m := range misses {
+			keys = append(keys, m.Key)
+		}
+		return keys
+	}
+	got := c.FrequentMisses(10)
+	if want := []string{"foo", "baz", "bar"}; !reflect.DeepEqual(keys(got), want) {
+		t.Errorf("cache.FrequentMisses() = %v, want %v", keys(got), want)
+	}
+	if got[0].Streak != 5 || got[1].Streak != 3 || got[2].Streak != 2 {
+		t.Errorf("cache.FrequentMisses() streaks = %d, %d, %d, want 5, 3, 2", got[0].Streak, got[1].Streak, got[2].Streak)
+	}
+	if got := c.FrequentMisses(1); !reflect.DeepEqual(keys(got), []string{"foo"}) {
+		t.Errorf("cache.FrequentMisses(1) = %v, want [foo]", keys(got))
+	}
+
+	// a hit ends the streak
+	c.Set("foo", "v", 1, 60)
+	c.Get("foo")
+	// the shortest streak is dropped for a new key
+	c.Get("zot")
+	c.Get("quux")
+	if got, want := keys(c.FrequentMisses(10)), []string{"baz", "bar", "quux"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.FrequentMisses() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package expirecache
+
+import "reflect"
+
+// Move moves the unexpired item for the key from src to dst under both locks, so it's never absent from both
+// or present in both. The size, remaining time to live, stickiness, priority, savings and tags are preserved,
+// the dependencies aren't (and the items depending on it in src are invalidated, like by Delete).
+// It returns false (and the item stays in src) if the item is absent from src, either cache is read-only, the item is
+// larger than the maximum memory size of dst, or the admission filter of dst rejects it (see SetAdmission).
+func Move[K comparable, T any](src, dst *Cache[K, T], k K) bool {
+	if src == dst {
+		return false
+	}
+	// lock in the address order, so concurrent moves in opposite directions don't deadlock
+	first, second := src, dst
+	if reflect.ValueOf(dst).Pointer() < reflect.ValueOf(src).Pointer() {
+		first, second = dst, src
+	}
+	now := timeNow()
+	first.Lock()
+	second.Lock()
+	v, ok := src.get(k)
+	if !ok || !src.alive(k, v, now) || src.readOnly || dst.readOnly || (dst.maxSize > 0 && v.size > dst.maxSize) {
+		second.Unlock()
+		first.Unlock()
+		return false
+	}
+	victim, hasVictim, admitted := dst.admit(k, v.size)
+	if !admitted {
+		second.Unlock()
+		first.Unlock()
+		return false
+	}
+	src.invalidateDependents(k)
+	src.removeAt(v.keyIdx)
+
+	opts := []SetOption{WithPriority(v.priority), WithSavings(v.savings), WithSavedLatency(v.latency), WithTags(v.tags...)}
+	if v.sticky {
+		opts = append(opts, Sticky())
+	}
+	dst.store(k, v.data, v.size, v.validUntil.Sub(now), victim, hasVictim, opts...)
+	src.Unlock()
+	// dispatches OnSpill of dst with no lock held
+	dst.unlock()
+	return true
+}
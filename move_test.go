@@ -0,0 +1,115 @@
+package expirecache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMove(t *testing.T) {
+	src := New[string, string](0)
+	dst := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	src.Set("foo", "bar", 3, 60, Sticky(), WithTags("x"))
+	src.Set("old", "bar", 3, 1)
+	timeNow = func() time.Time { return t0.Add(10 * time.Second) }
+
+	if !Move(src, dst, "foo") {
+		t.Fatalf("Move(foo) = false, want true")
+	}
+	if _, ok := src.Inspect("foo"); ok {
+		t.Errorf("src.Inspect(foo) after the move should miss")
+	}
+	info, ok := dst.Inspect("foo")
+	if !ok || info.Value != "bar" || info.Size != 3 || info.TTL != 50*time.Second {
+		t.Errorf("dst.Inspect(foo) = (%+v, %v), want bar of size 3 with TTL 50s", info, ok)
+	}
+	if src.Size() != 3 || dst.Size() != 3 {
+		t.Errorf("src, dst size = %d, %d, want 3, 3", src.Size(), dst.Size())
+	}
+	dst.Clear()
+	if got := dst.PopTag("x"); got["foo"] != "bar" {
+		t.Errorf("dst.PopTag(x) = %v, want the moved sticky item", got)
+	}
+
+	if Move(src, dst, "old") {
+		t.Errorf("Move(old) of the expired item = true, want false")
+	}
+	if Move(src, dst, "missing") {
+		t.Errorf("Move(missing) = true, want false")
+	}
+}
+
+func TestMoveRejected(t *testing.T) {
+	src := New[uint64, uint64](0)
+	dst := New[uint64, uint64](2)
+	dst.SetAdmission(func(k uint64) uint64 { return k }, 16)
+	dst.Set(1, 1, 1, 3600)
+	dst.Set(2, 2, 1, 3600)
+	for i := 0; i < 5; i++ {
+		dst.Get(1)
+		dst.Get(2)
+	}
+	src.Set(3, 3, 1, 3600)
+	src.Set(4, 4, 3, 3600)
+
+	// the cold key isn't admitted by dst, the too large one never fits
+	for _, k := range []uint64{3, 4} {
+		if Move(src, dst, k) {
+			t.Errorf("Move(%d) of the rejected item = true, want false", k)
+		}
+		if _, ok := src.Get(k); !ok {
+			t.Errorf("src.Get(%d) of the rejected item should stay present", k)
+		}
+		if _, ok := dst.Inspect(k); ok {
+			t.Errorf("dst.Inspect(%d) of the rejected item should miss", k)
+		}
+	}
+	if dst.Items() != 2 {
+		t.Errorf("dst items = %d, want 2", dst.Items())
+	}
+}
+
+func TestMoveConcurrent(t *testing.T) {
+	a := New[int, int](0)
+	b := New[int, int](0)
+	const items = 100
+	for i := 0; i < items; i++ {
+		a.Set(i, i, 1, 60)
+	}
+
+	// concurrent moves in both directions don't deadlock, each item stays in exactly one cache
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				if w%2 == 0 {
+					Move(a, b, n%items)
+				} else {
+					Move(b, a, n%items)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if a.Items()+b.Items() != items {
+		t.Errorf("items = %d + %d, want %d total", a.Items(), b.Items(), items)
+	}
+	for i := 0; i < items; i++ {
+		_, inA := a.Get(i)
+		_, inB := b.Get(i)
+		if inA == inB {
+			t.Errorf("item %d in a %v, in b %v, want in exactly one", i, inA, inB)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package expirecache
+
+import (
+	"strings"
+	"time"
+)
+
+// BumpNamespace invalidates all items of the namespace (string keys with the prefix) stored before the call,
+// without enumerating the keys. The reads (Get, GetOrSet, Range, Entries, SaveToWriter etc.) and the conditional
+// writes (SetIf, DeleteIf, ReplaceIfFits etc.) treat them like expired items, GetGrace misses them,
+// GetRevalidate serves them as stale and refreshes them, the cleaners remove them. It's a no-op for caches with non-string keys.
+func (ec *Cache[K, T]) BumpNamespace(prefix string) {
+	ec.Lock()
+	ec.epoch++
+	old, _ := ec.namespaces.Load().(map[string]uint64)
+	// published maps are read without a lock, so a copy replaces it
+	ns := make(map[string]uint64, len(old)+1)
+	for p, epoch := range old {
+		ns[p] = epoch
+	}
+	ns[prefix] = ec.epoch
+	ec.namespaces.Store(ns)
+	ec.Unlock()
+}
+
+// stale checks if the element was stored before a bump of its key namespace, safe to call with no lock held
+func (ec *Cache[K, T]) stale(k K, v *element[T]) bool {
+	ns, _ := ec.namespaces.Load().(map[string]uint64)
+	if len(ns) == 0 {
+		return false
+	}
+	s, isString := any(k).(string)
+	if !isString {
+		return false
+	}
+	for prefix, epoch := range ns {
+		if epoch > v.epoch && strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// alive checks if the element is neither expired nor stale, see stale
+func (ec *Cache[K, T]) alive(k K, v *element[T], now time.Time) bool {
+	return !v.validUntil.Before(now) && !ec.stale(k, v)
+}
@@ -0,0 +1,86 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheBumpNamespace(t *testing.T) {
+	for _, c := range []*Cache[string, string]{New[string, string](0), NewCopyOnWrite[string, string](0)} {
+		c.Set("user:1", "alice", 1, 60)
+		c.Set("user:2", "bob", 1, 60)
+		c.Set("order:1", "book", 1, 60)
+
+		c.BumpNamespace("user:")
+		c.Set("user:3", "carol", 1, 60)
+
+		for k, present := range map[string]bool{"user:1": false, "user:2": false, "user:3": true, "order:1": true} {
+			if _, ok := c.Get(k); ok != present {
+				t.Errorf("cache.Get(%s) = %v, want %v", k, ok, present)
+			}
+			if _, ok := c.Inspect(k); ok != present {
+				t.Errorf("cache.Inspect(%s) = %v, want %v", k, ok, present)
+			}
+		}
+		// stored again after the bump
+		if v := c.GetOrSet("user:1", "dave", 1, 60); v != "dave" {
+			t.Errorf("cache.GetOrSet(user:1) = %q, want %q", v, "dave")
+		}
+		if v, ok := c.Get("user:1"); !ok || v != "dave" {
+			t.Errorf("cache.Get(user:1) = (%q, %v), want (%q, true)", v, ok, "dave")
+		}
+
+		// the stale items are removed by the cleaner
+		c.cleanAll(time.Now())
+		if c.Items() != 3 {
+			t.Errorf("cache items = %d, want 3", c.Items())
+		}
+	}
+
+	// no namespaces for non-string keys
+	c := New[int, int](0)
+	c.Set(1, 1, 1, 60)
+	c.BumpNamespace("")
+	if _, ok := c.Get(1); !ok {
+		t.Errorf("cache.Get(1) should be present")
+	}
+}
+
+func TestCacheBumpNamespaceReads(t *testing.T) {
+	c := New[string, string](0)
+	c.Set("user:1", "alice", 1, 60)
+	c.Set("order:1", "book", 1, 60)
+	fresh := New[string, string](0)
+	fresh.Set("order:1", "book", 1, 60)
+	c.BumpNamespace("user:")
+
+	var visited []string
+	c.Range(func(k, v string) bool {
+		visited = append(visited, k)
+		return true
+	})
+	if len(visited) != 1 || visited[0] != "order:1" {
+		t.Errorf("cache.Range() visited %v, want [order:1]", visited)
+	}
+	if entries := c.Entries(); len(entries) != 1 || entries[0].Key != "order:1" {
+		t.Errorf("cache.Entries() = %+v, want only order:1", entries)
+	}
+	if c.Checksum() != fresh.Checksum() {
+		t.Errorf("cache.Checksum() should not include the stale items")
+	}
+	if _, _, ok := c.GetGrace("user:1"); ok {
+		t.Errorf("cache.GetGrace(user:1) should miss the stale item")
+	}
+	if c.DeleteIf("user:1", func(string) bool { return true }) {
+		t.Errorf("cache.DeleteIf(user:1) should not remove the stale item")
+	}
+	if c.ReplaceIfFits("user:1", "dave", 1, 60) {
+		t.Errorf("cache.ReplaceIfFits(user:1) should not replace the stale item")
+	}
+	if _, _, had := c.SwapWithOldTTL("user:1", "erin", 1, 60); had {
+		t.Errorf("cache.SwapWithOldTTL(user:1) should not return the stale item")
+	}
+	if v, ok := c.Get("user:1"); !ok || v != "erin" {
+		t.Errorf("cache.Get(user:1) = (%q, %v), want (%q, true)", v, ok, "erin")
+	}
+}
@@ -0,0 +1,60 @@
+package expirecache
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WithPreload makes the constructor load the keys with load and store them before returning the cache,
+// so the first lookups hit a warm cache. Up to parallelism keys (at least 1) are loaded concurrently, with no lock held.
+// The items aren't limited by the Set rate limit (see WithSetRateLimit). A load error isn't fatal: nothing is stored
+// for the key and onError (if not nil) is invoked with it, one call at a time, as for an item rejected
+// by the admission filter (with ErrNotAdmitted, see SetAdmission).
+// The option panics if the constructed cache doesn't have the K and T types of the option.
+func WithPreload[K comparable, T any](keys []K, load LoaderFunc[K, T], parallelism int, onError func(k K, err error)) Option {
+	return func(o *options) {
+		o.preload = func(c any) {
+			ec, ok := c.(*Cache[K, T])
+			if !ok {
+				panic(fmt.Sprintf("expirecache: WithPreload for %T, not for %T", (*Cache[K, T])(nil), c))
+			}
+			ec.preload(keys, load, parallelism, onError)
+		}
+	}
+}
+
+func (ec *Cache[K, T]) preload(keys []K, load LoaderFunc[K, T], parallelism int, onError func(k K, err error)) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	var (
+		wg    sync.WaitGroup
+		errMu sync.Mutex
+		sem   = make(chan struct{}, parallelism)
+	)
+	for _, k := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(k K) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			v, size, expire, err := load(k)
+			if err == nil {
+				// not limited by the Set rate limit, the known keys are loaded once
+				ec.Lock()
+				if !ec.readOnly && !ec.actualSet(k, v, size, ec.ttl(expire)) {
+					err = ErrNotAdmitted
+				}
+				ec.unlock()
+			}
+			if err != nil && onError != nil {
+				errMu.Lock()
+				onError(k, err)
+				errMu.Unlock()
+			}
+		}(k)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package expirecache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCachePreload(t *testing.T) {
+	errLoad := errors.New("load failed")
+	var loading, maxLoading int32
+	load := func(k int) (int, uint64, int32, error) {
+		n := atomic.AddInt32(&loading, 1)
+		defer atomic.AddInt32(&loading, -1)
+		for {
+			max := atomic.LoadInt32(&maxLoading)
+			if n <= max || atomic.CompareAndSwapInt32(&maxLoading, max, n) {
+				break
+			}
+		}
+		if k%10 == 0 {
+			return 0, 0, 0, errLoad
+		}
+		return k * 2, 1, 60, nil
+	}
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	for name, newCache := range map[string]func(opts ...Option) *Cache[int, int]{
+		"New":            func(opts ...Option) *Cache[int, int] { return New[int, int](0, opts...) },
+		"NewCopyOnWrite": func(opts ...Option) *Cache[int, int] { return NewCopyOnWrite[int, int](0, opts...) },
+	} {
+		failed := make(map[int]error)
+		maxLoading = 0
+		c := newCache(WithPreload(keys, load, 4, func(k int, err error) { failed[k] = err }))
+		for _, k := range keys {
+			v, ok := c.Get(k)
+			if k%10 == 0 {
+				if ok {
+					t.Errorf("%s: cache.Get(%d) of the failed key should miss", name, k)
+				}
+				if failed[k] != errLoad {
+					t.Errorf("%s: the error of %d = %v, want %v", name, k, failed[k], errLoad)
+				}
+			} else if !ok || v != k*2 {
+				t.Errorf("%s: cache.Get(%d) = (%d, %v), want (%d, true)", name, k, v, ok, k*2)
+			}
+		}
+		if len(failed) != 10 {
+			t.Errorf("%s: failed keys = %d, want 10", name, len(failed))
+		}
+		if maxLoading > 4 {
+			t.Errorf("%s: concurrent loads = %d, want at most 4", name, maxLoading)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("New with WithPreload of the other types should panic")
+		}
+	}()
+	New[string, int](0, WithPreload(keys, load, 1, nil))
+}
+
+func TestCachePreloadRateLimit(t *testing.T) {
+	keys := []int{1, 2, 3, 4, 5}
+	load := func(k int) (int, uint64, int32, error) { return k, 1, 60, nil }
+	var failed int
+	c := New[int, int](0, WithSetRateLimit(1, 1), WithPreload(keys, load, 2, func(k int, err error) { failed++ }))
+	// the preloaded items aren't limited
+	if c.Items() != len(keys) || failed != 0 {
+		t.Errorf("preloaded items = %d, failed %d, want %d, 0", c.Items(), failed, len(keys))
+	}
+	if st := c.Stats(); st.RateLimited != 0 {
+		t.Errorf("rate limited = %d, want 0", st.RateLimited)
+	}
+}
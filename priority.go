@@ -0,0 +1,67 @@
+package expirecache
+
+import "sync/atomic"
+
+// Priority is an item eviction priority band
+type Priority int8
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+
+	priorityBands = int(PriorityHigh-PriorityLow) + 1
+)
+
+// WithPriority sets the item eviction priority (PriorityNormal by default).
+//
+// While all items have the same priority a random item is evicted. With mixed priorities the least recently used
+// item of the lowest non-empty band is evicted, regardless of recency in the other bands. This is found with a scan
+// over all items, so evictions with mixed priorities are O(n).
+func WithPriority(p Priority) SetOption {
+	if p < PriorityLow {
+		p = PriorityLow
+	} else if p > PriorityHigh {
+		p = PriorityHigh
+	}
+	return func(o *setOptions) {
+		o.priority = p
+	}
+}
+
+// mixedPriorities checks if items have more than one priority
+func (ec *Cache[K, T]) mixedPriorities() bool {
+	for _, n := range ec.priorities {
+		if n == len(ec.keys) {
+			return false
+		}
+	}
+	return true
+}
+
+// priorityVictim returns the index in ec.keys of the least recently used item in the lowest non-empty priority band
+// (skipping the vetoed items, see SetVetoEvict), -1 if all items are vetoed
+func (ec *Cache[K, T]) priorityVictim() int {
+	victim := -1
+	for band := PriorityLow; victim == -1 && band <= PriorityHigh; band++ {
+		if ec.priorities[band-PriorityLow] == 0 {
+			continue
+		}
+		var victimAccess int64
+		for i, k := range ec.keys {
+			v := ec.elem(k)
+			if v.priority != band || ec.vetoed(i) {
+				continue
+			}
+			accessed := atomic.LoadInt64(&v.lastAccess)
+			if accessed == 0 {
+				accessed = v.created.UnixNano()
+			}
+			if victim == -1 || accessed < victimAccess {
+				victim = i
+				victimAccess = accessed
+			}
+		}
+	}
+	return victim
+}
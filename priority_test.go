@@ -0,0 +1,75 @@
+package expirecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCachePriority(t *testing.T) {
+	c := New[string, int](10)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	now := t0
+	timeNow = func() time.Time { return now }
+	tick := func() {
+		now = now.Add(time.Second)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("high%d", i), i, 1, 3600, WithPriority(PriorityHigh))
+		tick()
+	}
+	for i := 0; i < 4; i++ {
+		c.Set(fmt.Sprintf("normal%d", i), i, 1, 3600)
+		tick()
+	}
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("low%d", i), i, 1, 3600, WithPriority(PriorityLow))
+		tick()
+	}
+
+	// low1 is the least recently used low item after this
+	c.Get("low0")
+	tick()
+	c.Get("low2")
+	tick()
+	c.Get("normal0")
+	tick()
+
+	check := func(name string, present map[string]bool) {
+		t.Helper()
+		for k, want := range present {
+			if _, ok := c.Inspect(k); ok != want {
+				t.Errorf("%s: %s present = %v, want %v", name, k, ok, want)
+			}
+		}
+	}
+
+	c.Set("new0", 0, 1, 3600)
+	tick()
+	check("overflow 1", map[string]bool{"low0": true, "low1": false, "low2": true})
+
+	c.Set("new1", 0, 2, 3600)
+	tick()
+	check("overflow 2", map[string]bool{"low0": false, "low2": false})
+
+	// normal band in LRU order: normal1, normal2, normal3, new0, new1, normal0
+	c.Set("new2", 0, 2, 3600, WithPriority(PriorityHigh))
+	tick()
+	check("overflow 3", map[string]bool{"normal0": true, "normal1": false, "normal2": false, "normal3": true})
+
+	for i := 0; i < 3; i++ {
+		check("high", map[string]bool{fmt.Sprintf("high%d", i): true})
+	}
+	if c.Items() != 8 || c.Size() != 10 {
+		t.Errorf("items, size = %d, %d, want 8, 10", c.Items(), c.Size())
+	}
+	if c.priorities != [priorityBands]int{0, 4, 4} {
+		t.Errorf("priority bands = %v, want [0 4 4]", c.priorities)
+	}
+}
@@ -0,0 +1,28 @@
+package expirecache
+
+import "time"
+
+// SetHotPromotion enables extending the expiration time of hot items: when an item gets its hits-th hit
+// (Get, GetOrSet, ComputeIfAbsent, GetGrace, GetRevalidate), its expiration time is extended by extend. It's done once per stored item,
+// storing the item again resets its hits count. Pass 0 hits to disable.
+func (ec *Cache[K, T]) SetHotPromotion(hits uint64, extend time.Duration) {
+	ec.Lock()
+	ec.promoteHits = hits
+	ec.promoteTTL = extend
+	ec.Unlock()
+}
+
+// promote extends the expiration time of the element, unless it's already replaced, removed or expired
+func (ec *Cache[K, T]) promote(k K, v *element[T]) {
+	now := timeNow()
+	ec.Lock()
+	if ec.elem(k) == v && !ec.readOnly && ec.alive(k, v, now) {
+		ec.extend(k, v)
+	}
+	ec.Unlock()
+}
+
+// extend extends the expiration time of the element by the promotion TTL, the caller holds the write lock
+func (ec *Cache[K, T]) extend(k K, v *element[T]) {
+	ec.expiresAt(k, v, v.validUntil.Add(ec.promoteTTL))
+}
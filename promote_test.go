@@ -0,0 +1,102 @@
+package expirecache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheHotPromotion(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.SetHotPromotion(3, time.Minute)
+	c.Set("hot", "bar", 3, 10)
+	c.Set("cold", "bar", 3, 10)
+	c.Set("other", "bar", 3, 10)
+	c.Set("computed", "bar", 3, 10)
+	c.Set("grace", "bar", 3, 10)
+	c.Set("revalidated", "bar", 3, 10)
+
+	c.Get("cold")
+	for i := 0; i < 5; i++ {
+		c.Get("hot")
+	}
+	for i := 0; i < 3; i++ {
+		c.GetOrSet("other", "baz", 3, 10)
+		c.ComputeIfAbsent("computed", 3, 10, func() (string, error) { return "baz", nil })
+		c.GetGrace("grace")
+		c.GetRevalidate("revalidated", nil)
+	}
+
+	want := map[string]time.Duration{
+		"hot": 70 * time.Second, "cold": 10 * time.Second, "other": 70 * time.Second,
+		"computed": 70 * time.Second, "grace": 70 * time.Second, "revalidated": 70 * time.Second,
+	}
+	for k, ttl := range want {
+		if info, _ := c.Inspect(k); info.TTL != ttl {
+			t.Errorf("cache.Inspect(%s) TTL = %v, want %v", k, info.TTL, ttl)
+		}
+	}
+	if next, _ := c.NextExpiry(); !next.Equal(t0.Add(10 * time.Second)) {
+		t.Errorf("cache.NextExpiry() = %v, want %v", next, t0.Add(10*time.Second))
+	}
+
+	timeNow = func() time.Time { return t0.Add(time.Minute) }
+	c.cleanAll(timeNow())
+	if _, ok := c.Get("hot"); !ok {
+		t.Errorf("cache.Get(hot) after the original TTL should hit")
+	}
+	if _, ok := c.Get("cold"); ok {
+		t.Errorf("cache.Get(cold) after the TTL should miss")
+	}
+
+	// storing again resets the hits count
+	c.Set("hot", "bar", 3, 10)
+	c.Get("hot")
+	if info, _ := c.Inspect("hot"); info.TTL != 10*time.Second {
+		t.Errorf("cache.Inspect(hot) TTL = %v after the overwrite, want 10s", info.TTL)
+	}
+}
+
+func TestCacheHotPromotionExpired(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.SetHotPromotion(1, time.Minute)
+	c.SetGracePeriod(time.Minute)
+	c.Set("grace", "bar", 3, 10)
+	c.Set("stale", "bar", 3, 10)
+
+	// hits of the expired item within the grace period
+	timeNow = func() time.Time { return t0.Add(30 * time.Second) }
+	if _, grace, ok := c.GetGrace("grace"); !ok || !grace {
+		t.Errorf("cache.GetGrace(grace) = (%v, %v), want (true, true)", grace, ok)
+	}
+	if _, ok := c.Get("grace"); ok {
+		t.Errorf("cache.Get(grace) of the expired item should miss after GetGrace")
+	}
+
+	// hits of the item marked stale
+	timeNow = func() time.Time { return t0 }
+	c.MarkStale("stale")
+	load := func(k string) (string, uint64, int32, error) { return "", 0, 0, errors.New("no refresh") }
+	if _, stale, ok := c.GetRevalidate("stale", load); !ok || !stale {
+		t.Errorf("cache.GetRevalidate(stale) = (%v, %v), want (true, true)", stale, ok)
+	}
+	if _, ok := c.Get("stale"); ok {
+		t.Errorf("cache.Get(stale) of the item marked stale should miss after GetRevalidate")
+	}
+}
@@ -0,0 +1,65 @@
+package expirecache
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned by Sets with an error result rejected due to the rate limit, see WithSetRateLimit
+var ErrRateLimited = errors.New("expirecache: set rate limit exceeded")
+
+// WithSetRateLimit limits the rate of Sets to rate per second, with bursts of up to burst Sets (at least 1).
+// All stores of new values are limited: Set and its variants, GetOrSet, SetIf, ReplaceIfFits, SwapWithOldTTL, Apply,
+// IncrementWindow creating a counter, WriteBuffer flushes and the stores of the loaded values (ComputeIfAbsent,
+// Fetch, GetOrLoad, GetManyReadThrough, RangeLoad, GetRevalidate refreshes). Sets over the limit
+// are rejected: Set drops the item, TrySet, SetIf and ReplaceIfFits return false, SetAuto, SetCtx and SetTier
+// return ErrRateLimited, the others return (or keep) the value without storing it. The rejected Sets are counted
+// in Stats.RateLimited. The copies of the stored items (LoadFromReader, StreamTo, Move) and preloading (see WithPreload)
+// aren't limited, as they restore, move or warm up a known set of items rather than produce new ones.
+func WithSetRateLimit(rate float64, burst int) Option {
+	return func(o *options) {
+		o.setRate = rate
+		o.setBurst = burst
+	}
+}
+
+// tokenBucket is a token bucket rate limiter, must be used under the lock
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// allow takes a token if there is one
+func (b *tokenBucket) allow(now time.Time) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// allowSet checks the Set rate limit and counts a rejected Set, must be called under the write lock
+func (ec *Cache[K, T]) allowSet() bool {
+	if ec.setLimit == nil || ec.setLimit.allow(timeNow()) {
+		return true
+	}
+	atomic.AddUint64(&ec.stats.RateLimited, 1)
+	return false
+}
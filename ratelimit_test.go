@@ -0,0 +1,125 @@
+package expirecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheSetRateLimit(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c := New[int, int](0, WithSetRateLimit(10, 5))
+
+	// the burst, then nothing until the tokens are refilled
+	var allowed int
+	for i := 0; i < 100; i++ {
+		if c.TrySet(i, i, 1, 60) {
+			allowed++
+		}
+	}
+	if allowed != 5 || c.Items() != 5 {
+		t.Errorf("allowed Sets = %d, items %d, want 5, 5", allowed, c.Items())
+	}
+	c.Set(100, 100, 1, 60)
+	if _, ok := c.Get(100); ok {
+		t.Errorf("cache.Get(100) of the rejected Set should miss")
+	}
+	if err := c.SetAuto(100, 100, 60); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("cache.SetAuto(100) error = %v, want %v", err, ErrRateLimited)
+	}
+	if err := c.SetCtx(context.Background(), 100, 100, 1, 60); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("cache.SetCtx(100) error = %v, want %v", err, ErrRateLimited)
+	}
+
+	// 10 Sets per second at 100 Sets per second
+	allowed = 0
+	for i := 0; i < 100; i++ {
+		at := t0.Add(time.Duration(i/10+1) * 100 * time.Millisecond)
+		timeNow = func() time.Time { return at }
+		if c.TrySet(1000+i, i, 1, 60) {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Errorf("allowed Sets in a second = %d, want 10", allowed)
+	}
+	if st := c.Stats(); st.RateLimited != 95+3+90 {
+		t.Errorf("rate limited = %d, want %d", st.RateLimited, 95+3+90)
+	}
+
+	// the tokens are capped by the burst
+	timeNow = func() time.Time { return t0.Add(time.Hour) }
+	allowed = 0
+	for i := 0; i < 100; i++ {
+		if c.TrySet(i, i, 1, 60) {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("allowed Sets after an idle hour = %d, want 5", allowed)
+	}
+	if cfg := c.Config(); cfg.SetRateLimit != 10 || cfg.SetBurst != 5 {
+		t.Errorf("cache.Config() rate limit = %v, burst %d, want 10, 5", cfg.SetRateLimit, cfg.SetBurst)
+	}
+}
+
+func TestCacheSetRateLimitWritePaths(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c := New[int, int64](0, WithSetRateLimit(1, 1))
+	c.Set(0, 0, 1, 60)
+	// no tokens left, the clock doesn't move
+	always := func(int64, bool) bool { return true }
+	load := func() (int64, error) { return 1, nil }
+	stores := map[string]func(){
+		"GetOrSet":       func() { c.GetOrSet(1, 1, 1, 60) },
+		"SetIf":          func() { c.SetIf(2, 1, 1, 60, always) },
+		"ReplaceIfFits":  func() { c.ReplaceIfFits(0, 1, 1, 60) },
+		"SwapWithOldTTL": func() { c.SwapWithOldTTL(3, 1, 1, 60) },
+		"Apply":          func() { c.Apply([]Mutation[int, int64]{{Key: 4, Value: 1, Size: 1, Expire: 60}}) },
+		"IncrementWindow": func() {
+			if count, _ := IncrementWindow(c, 5, 1, 60); count != 0 {
+				t.Errorf("IncrementWindow(5) = %d, want 0 over the rate limit", count)
+			}
+		},
+		"ComputeIfAbsent": func() { c.ComputeIfAbsent(6, 1, 60, load) },
+		"WriteBuffer": func() {
+			wb := NewWriteBuffer(c, 0)
+			wb.Set(7, 1, 1, 60)
+			if n := wb.Flush(); n != 0 {
+				t.Errorf("wb.Flush() = %d, want 0 over the rate limit", n)
+			}
+		},
+	}
+	for name, store := range stores {
+		store()
+		if c.Items() != 1 {
+			t.Fatalf("%s over the rate limit stored an item, items = %d", name, c.Items())
+		}
+	}
+	if v, _ := c.Get(0); v != 0 {
+		t.Errorf("cache.Get(0) = %d, want 0 (ReplaceIfFits over the rate limit)", v)
+	}
+	if st := c.Stats(); st.RateLimited != uint64(len(stores)) {
+		t.Errorf("rate limited = %d, want %d", st.RateLimited, len(stores))
+	}
+
+	// the increments of an existing counter aren't limited
+	timeNow = func() time.Time { return t0.Add(time.Second) }
+	IncrementWindow(c, 5, 1, 60)
+	if count, _ := IncrementWindow(c, 5, 1, 60); count != 2 {
+		t.Errorf("IncrementWindow(5) = %d, want 2", count)
+	}
+}
@@ -0,0 +1,91 @@
+package expirecache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// GetRevalidate returns the item from the cache, serving it stale-while-revalidate:
+// an expired item (not yet removed by a cleaner) is returned immediately with stale set to true,
+// and refreshed with load in a background goroutine, so the next lookups get the fresh item.
+// Concurrent refreshes of the same key are deduplicated, load errors (and panics) keep the stale item.
+// An absent item is a miss, load isn't called for it.
+func (ec *Cache[K, T]) GetRevalidate(k K, load LoaderFunc[K, T]) (item T, stale, ok bool) {
+	now := timeNow()
+	ec.RLock()
+	v, ok := ec.get(k)
+	if !ok {
+		ec.RUnlock()
+		ec.lookup(k, false)
+		return item, false, false
+	}
+	item = v.data
+	stale = !ec.alive(k, v, now)
+	// a stale item isn't promoted, it would be brought back to life
+	promote := ec.touch(v, now) && !stale
+	ec.RUnlock()
+	if promote {
+		ec.promote(k, v)
+	}
+	ec.lookup(k, true)
+
+	if stale {
+		ec.revalidate(k, load)
+	}
+	return item, stale, true
+}
+
+// MarkStale expires the unexpired item now, e.g. when it's known to be outdated, without removing it:
+// Get misses it, GetRevalidate serves it as stale and refreshes it, and GetGrace serves it until the original
+// expiration time (plus the grace period, see SetGracePeriod), when the cleaners remove it.
+// It returns false if the item is absent or expired, or the cache is read-only.
+func (ec *Cache[K, T]) MarkStale(k K) bool {
+	now := timeNow()
+	ec.Lock()
+	v, ok := ec.get(k)
+	if ec.readOnly || !ok || !ec.alive(k, v, now) {
+		ec.Unlock()
+		return false
+	}
+	v = ec.mutate(k, v, func(v *element[T]) {
+		v.hardUntil = v.validUntil
+		// expired at now, the expiration time itself is still valid
+		v.validUntil = now.Add(-time.Nanosecond)
+	})
+	heap.Fix(&ec.expiry, v.heapIdx)
+	ec.Unlock()
+	return true
+}
+
+// revalidate starts a background refresh of the key, unless it's already running
+func (ec *Cache[K, T]) revalidate(k K, load LoaderFunc[K, T]) {
+	ec.Lock()
+	if _, running := ec.refreshing[k]; running || ec.readOnly {
+		ec.Unlock()
+		return
+	}
+	if ec.refreshing == nil {
+		ec.refreshing = make(map[K]struct{})
+	}
+	ec.refreshing[k] = struct{}{}
+	ec.Unlock()
+
+	go func() {
+		var (
+			size   uint64
+			expire int32
+		)
+		v, err := safeCall(func() (v T, err error) {
+			v, size, expire, err = load(k)
+			return v, err
+		})
+		ec.Lock()
+		delete(ec.refreshing, k)
+		if err != nil || ec.readOnly || !ec.allowSet() {
+			ec.Unlock()
+			return
+		}
+		ec.actualSet(k, v, size, ec.ttl(expire))
+		ec.unlock()
+	}()
+}
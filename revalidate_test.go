@@ -0,0 +1,146 @@
+package expirecache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheGetRevalidate(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	var loads int32
+	release := make(chan struct{})
+	loaded := make(chan struct{})
+	load := func(k string) (string, uint64, int32, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		defer close(loaded)
+		return "fresh", 5, 60, nil
+	}
+
+	c.Set("foo", "bar", 3, 30)
+
+	if v, stale, ok := c.GetRevalidate("foo", load); !ok || stale || v != "bar" {
+		t.Errorf("GetRevalidate(foo) = (%v, %v, %v), want (bar, false, true)", v, stale, ok)
+	}
+	if _, _, ok := c.GetRevalidate("baz", load); ok {
+		t.Errorf("GetRevalidate(baz) should miss")
+	}
+
+	timeNow = func() time.Time { return t0.Add(time.Minute) }
+
+	// stale value is served immediately, while the loader is blocked
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, stale, ok := c.GetRevalidate("foo", load); !ok || !stale || v != "bar" {
+				t.Errorf("GetRevalidate(foo) = (%v, %v, %v), want (bar, true, true)", v, stale, ok)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	select {
+	case <-loaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("background refresh should be done")
+	}
+	// wait for the refresh to be stored
+	for i := 0; i < 1000; i++ {
+		if v, _ := c.Get("foo"); v == "fresh" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("loader calls = %d, want 1", n)
+	}
+	if v, stale, ok := c.GetRevalidate("foo", load); !ok || stale || v != "fresh" {
+		t.Errorf("GetRevalidate(foo) after refresh = (%v, %v, %v), want (fresh, false, true)", v, stale, ok)
+	}
+	if c.Size() != 5 {
+		t.Errorf("size = %d, want 5", c.Size())
+	}
+}
+
+func TestCacheMarkStale(t *testing.T) {
+	for name, c := range map[string]*Cache[string, string]{
+		"New":            New[string, string](0),
+		"NewCopyOnWrite": NewCopyOnWrite[string, string](0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				timeNow = time.Now
+			}()
+			t0 := time.Now()
+			timeNow = func() time.Time { return t0 }
+
+			loaded := make(chan struct{})
+			load := func(k string) (string, uint64, int32, error) {
+				defer close(loaded)
+				return "fresh", 1, 60, nil
+			}
+
+			c.Set("foo", "bar", 1, 30)
+			if c.MarkStale("baz") {
+				t.Errorf("cache.MarkStale(baz) of the absent item = true, want false")
+			}
+			if !c.MarkStale("foo") {
+				t.Fatalf("cache.MarkStale(foo) = false, want true")
+			}
+			if _, ok := c.Get("foo"); ok {
+				t.Errorf("cache.Get(foo) of the stale item should miss")
+			}
+			if c.MarkStale("foo") {
+				t.Errorf("cache.MarkStale(foo) of the stale item = true, want false")
+			}
+
+			// kept until the original expiration time
+			c.cleanAll(t0.Add(29 * time.Second))
+			timeNow = func() time.Time { return t0.Add(29 * time.Second) }
+			if v, grace, ok := c.GetGrace("foo"); !ok || !grace || v != "bar" {
+				t.Errorf("cache.GetGrace(foo) = (%v, %v, %v), want (bar, true, true)", v, grace, ok)
+			}
+
+			// served stale and refreshed
+			if v, stale, ok := c.GetRevalidate("foo", load); !ok || !stale || v != "bar" {
+				t.Errorf("cache.GetRevalidate(foo) = (%v, %v, %v), want (bar, true, true)", v, stale, ok)
+			}
+			select {
+			case <-loaded:
+			case <-time.After(5 * time.Second):
+				t.Fatal("background refresh should be done")
+			}
+			for i := 0; i < 1000; i++ {
+				if v, _ := c.Get("foo"); v == "fresh" {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if v, ok := c.Get("foo"); !ok || v != "fresh" {
+				t.Errorf("cache.Get(foo) after the refresh = (%v, %v), want (fresh, true)", v, ok)
+			}
+
+			// removed by the cleaners at the original expiration time
+			c.Set("baz", "qux", 1, 30)
+			c.MarkStale("baz")
+			c.cleanAll(t0.Add(time.Minute))
+			if _, ok := c.get("baz"); ok {
+				t.Errorf("cache.get(baz) after the original expiration time should be removed")
+			}
+		})
+	}
+}
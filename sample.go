@@ -0,0 +1,66 @@
+package expirecache
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+)
+
+// weightedKey is a key with its sampling score
+type weightedKey[K comparable] struct {
+	k     K
+	score float64
+}
+
+// weightedKeys is a min-heap of keys ordered by the sampling score
+type weightedKeys[K comparable] []weightedKey[K]
+
+func (h weightedKeys[K]) Len() int { return len(h) }
+
+func (h weightedKeys[K]) Less(i, j int) bool { return h[i].score < h[j].score }
+
+func (h weightedKeys[K]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *weightedKeys[K]) Push(x any) { *h = append(*h, x.(weightedKey[K])) }
+
+func (h *weightedKeys[K]) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// SampleWeighted returns up to n distinct keys of the unexpired items, sampled with the probability
+// proportional to their sizes (items of zero size are never sampled), e.g. for finding the memory hogs.
+// It's a single pass over the items keeping only n candidates, with no sorting of all items.
+func (ec *Cache[K, T]) SampleWeighted(n int) []K {
+	if n <= 0 {
+		return nil
+	}
+	now := timeNow()
+	h := make(weightedKeys[K], 0, n)
+	ec.RLock()
+	for _, k := range ec.keys {
+		v := ec.elem(k)
+		if v.size == 0 || !ec.alive(k, v, now) {
+			continue
+		}
+		// the Efraimidis-Spirakis weighted reservoir: the n keys with the highest u^(1/size),
+		// compared as log(u)/size to avoid the underflow
+		score := math.Log(rand.Float64()) / float64(v.size)
+		if len(h) < n {
+			heap.Push(&h, weightedKey[K]{k: k, score: score})
+		} else if score > h[0].score {
+			h[0] = weightedKey[K]{k: k, score: score}
+			heap.Fix(&h, 0)
+		}
+	}
+	ec.RUnlock()
+
+	keys := make([]K, len(h))
+	for i := range h {
+		keys[i] = h[i].k
+	}
+	return keys
+}
@@ -0,0 +1,50 @@
+package expirecache
+
+import "testing"
+
+func TestCacheSampleWeighted(t *testing.T) {
+	c := New[int, int](0)
+
+	if keys := c.SampleWeighted(1); len(keys) != 0 {
+		t.Errorf("cache.SampleWeighted(1) of the empty cache = %v, want empty", keys)
+	}
+
+	// key 0 has the half of the total size
+	c.Set(0, 0, 100, 60)
+	for i := 1; i <= 100; i++ {
+		c.Set(i, i, 1, 60)
+	}
+	c.Set(-1, -1, 0, 60)
+	c.Set(-2, -2, 1000, -1) // expired
+
+	const runs = 1000
+	var big int
+	for r := 0; r < runs; r++ {
+		keys := c.SampleWeighted(1)
+		if len(keys) != 1 {
+			t.Fatalf("cache.SampleWeighted(1) = %v, want 1 key", keys)
+		}
+		switch keys[0] {
+		case 0:
+			big++
+		case -1, -2:
+			t.Fatalf("cache.SampleWeighted(1) = %v, sampled a zero size or expired item", keys)
+		}
+	}
+	// expected half of the runs, a key of size 1 would be sampled 1% of the runs
+	if big < runs/3 || big > runs*2/3 {
+		t.Errorf("big item sampled %d times of %d, want about a half", big, runs)
+	}
+
+	keys := c.SampleWeighted(200)
+	if len(keys) != 101 {
+		t.Errorf("cache.SampleWeighted(200) = %d keys, want all 101 sampleable", len(keys))
+	}
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if seen[k] {
+			t.Errorf("cache.SampleWeighted(200) returned %d twice", k)
+		}
+		seen[k] = true
+	}
+}
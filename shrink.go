@@ -0,0 +1,63 @@
+package expirecache
+
+import "math/rand"
+
+// ShrinkFunc returns a smaller version of the value with its size, e.g. an image at a lower resolution.
+// ok is false if the value can't be shrunk.
+type ShrinkFunc[K comparable, T any] func(k K, v T) (shrunk T, size uint64, ok bool)
+
+// SetShrinkFunc sets a function for shrinking items instead of evicting them due to the maximum memory size:
+// an item chosen for eviction is replaced with its shrunk version. If it can't be shrunk (f returns false
+// or doesn't reduce the size), another item is shrunk instead, so the chosen item is evicted only if none can be.
+// Finding another item is O(n), the value which can't be shrunk isn't passed to f again until stored anew.
+// f is invoked under the write lock, so it must not call back into the cache.
+// Pass nil to disable.
+func (ec *Cache[K, T]) SetShrinkFunc(f ShrinkFunc[K, T]) {
+	ec.Lock()
+	ec.shrink = f
+	ec.Unlock()
+}
+
+// shrinkAt shrinks the item for the key at slot in ec.keys, it returns false if the item isn't shrunk
+func (ec *Cache[K, T]) shrinkAt(slot int) bool {
+	if ec.shrink == nil {
+		return false
+	}
+	k := ec.keys[slot]
+	v := ec.elem(k)
+	if v.unshrinkable {
+		return false
+	}
+	if v.interned != nil {
+		// the shared copy is accounted once, it's evicted with the last reference
+		return false
+	}
+	data, size, ok := ec.shrink(k, v.data)
+	if !ok || size >= v.size {
+		// the flag isn't read with no lock held, so it's set in place
+		v.unshrinkable = true
+		return false
+	}
+	ec.totalSize -= v.size - size
+	ec.mutate(k, v, func(v *element[T]) {
+		v.data = data
+		v.size = size
+	})
+	ec.freed()
+	return true
+}
+
+// shrinkAny shrinks the first item which can be shrunk from a random start, skipping the vetoed ones (see SetVetoEvict).
+// It returns false if no item is shrunk.
+func (ec *Cache[K, T]) shrinkAny() bool {
+	if ec.shrink == nil || len(ec.keys) == 0 {
+		return false
+	}
+	start := rand.Intn(len(ec.keys))
+	for i := range ec.keys {
+		if slot := (start + i) % len(ec.keys); !ec.vetoed(slot) && ec.shrinkAt(slot) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package expirecache
+
+import "testing"
+
+func TestCacheShrink(t *testing.T) {
+	c := New[int, string](100)
+
+	var shrunk int
+	c.SetShrinkFunc(func(k int, v string) (string, uint64, bool) {
+		if len(v) < 2 {
+			return v, 0, false
+		}
+		shrunk++
+		v = v[:len(v)/2]
+		return v, uint64(len(v)), true
+	})
+
+	for i := 0; i < 10; i++ {
+		c.Set(i, "0123456789", 10, 60)
+	}
+	c.Set(10, "0123456789", 10, 60)
+
+	if shrunk == 0 {
+		t.Errorf("no items shrunk")
+	}
+	if st := c.Stats(); st.Evictions != 0 {
+		t.Errorf("evictions = %d, want 0", st.Evictions)
+	}
+	if c.Items() != 11 {
+		t.Errorf("items = %d, want 11", c.Items())
+	}
+	var size uint64
+	for i := 0; i < 11; i++ {
+		info, ok := c.Inspect(i)
+		if !ok {
+			t.Fatalf("cache.Inspect(%d) should be present", i)
+		}
+		if info.Size != uint64(len(info.Value)) {
+			t.Errorf("cache.Inspect(%d) size = %d, want %d", i, info.Size, len(info.Value))
+		}
+		size += info.Size
+	}
+	if c.Size() != size || size > 100 {
+		t.Errorf("size = %d, want %d <= 100", c.Size(), size)
+	}
+
+	// items which can't be shrunk are evicted
+	c.Set(11, "x", 100, 60)
+	if st := c.Stats(); st.Evictions == 0 {
+		t.Errorf("evictions = 0, want the items evicted")
+	}
+	if c.Size() > 100 {
+		t.Errorf("size = %d, want <= 100", c.Size())
+	}
+}
+
+func TestCacheShrinkBeforeEvict(t *testing.T) {
+	c := New[int, string](100)
+	c.SetShrinkFunc(func(k int, v string) (string, uint64, bool) {
+		if len(v) < 2 {
+			return v, 0, false
+		}
+		v = v[:len(v)/2]
+		return v, uint64(len(v)), true
+	})
+
+	// several rounds of the pressure, shrinking the items down to a single byte
+	var evictions uint64
+	for i := 0; i < 150; i++ {
+		c.Set(i, "0123456789", 10, 60)
+		if st := c.Stats(); st.Evictions != evictions {
+			evictions = st.Evictions
+			// all items are shrunk before any is evicted
+			for j := 0; j <= i; j++ {
+				if info, ok := c.Inspect(j); ok && len(info.Value) > 1 {
+					t.Fatalf("cache.Set(%d) evicted an item while %d = %q can be shrunk", i, j, info.Value)
+				}
+			}
+		}
+		if c.Size() > 100 {
+			t.Fatalf("size = %d after cache.Set(%d), want <= 100", c.Size(), i)
+		}
+	}
+	if evictions == 0 {
+		t.Errorf("evictions = 0, want the items evicted once all are shrunk")
+	}
+}
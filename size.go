@@ -0,0 +1,74 @@
+package expirecache
+
+import (
+	"encoding/gob"
+	"fmt"
+)
+
+// byteCounter is an io.Writer counting written bytes
+type byteCounter uint64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// gobSize returns the gob-encoded length of v
+func gobSize[T any](v T) (uint64, error) {
+	var n byteCounter
+	if err := gob.NewEncoder(&n).Encode(&v); err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// SetSizeFunc sets the function estimating item sizes for SetAuto (nil to restore the default gob-encoded length).
+// It's called with no lock held.
+func (ec *Cache[K, T]) SetSizeFunc(f func(v T) uint64) {
+	ec.Lock()
+	ec.sizeFunc = f
+	ec.Unlock()
+}
+
+// autoSize estimates the item size with sizeFunc, or the gob-encoded length if it's nil
+func autoSize[K comparable, T any](k K, v T, sizeFunc func(v T) uint64) (uint64, error) {
+	if sizeFunc != nil {
+		return sizeFunc(v), nil
+	}
+	size, err := gobSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("expirecache: size of %v: %w", k, err)
+	}
+	return size, nil
+}
+
+// SetAuto adds an item to the cache like Set, with the size estimated by the SetSizeFunc function
+// (the gob-encoded length by default). An error is returned if the default gob encoding fails
+// or the cache is read-only.
+func (ec *Cache[K, T]) SetAuto(k K, v T, expire int32, opts ...SetOption) error {
+	ec.RLock()
+	sizeFunc := ec.sizeFunc
+	ec.RUnlock()
+
+	size, err := autoSize(k, v, sizeFunc)
+	if err != nil {
+		return err
+	}
+
+	ec.Lock()
+	if ec.readOnly {
+		err := ec.errReadOnly()
+		ec.Unlock()
+		return err
+	}
+	if !ec.allowSet() {
+		ec.Unlock()
+		return ErrRateLimited
+	}
+	stored := ec.actualSet(k, v, size, ec.ttl(expire), opts...)
+	ec.unlock()
+	if !stored {
+		return ErrNotAdmitted
+	}
+	return nil
+}
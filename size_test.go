@@ -0,0 +1,70 @@
+package expirecache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+type sizedValue struct {
+	Name    string
+	Payload []byte
+}
+
+func TestCacheSetAuto(t *testing.T) {
+	c := New[string, sizedValue](0)
+
+	encodedSize := func(v sizedValue) uint64 {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+			t.Fatalf("gob encode error = %v", err)
+		}
+		return uint64(buf.Len())
+	}
+
+	foo := sizedValue{Name: "foo", Payload: make([]byte, 100)}
+	bar := sizedValue{Name: "bar", Payload: make([]byte, 1000)}
+
+	// default gob-encoded length
+	if err := c.SetAuto("foo", foo, 60); err != nil {
+		t.Fatalf("SetAuto(foo) error = %v", err)
+	}
+	if info, _ := c.Inspect("foo"); info.Size != encodedSize(foo) {
+		t.Errorf("SetAuto(foo) size = %d, want %d", info.Size, encodedSize(foo))
+	}
+
+	// custom estimation
+	var calls int
+	c.SetSizeFunc(func(v sizedValue) uint64 {
+		calls++
+		return uint64(len(v.Name) + len(v.Payload))
+	})
+	if err := c.SetAuto("bar", bar, 60); err != nil {
+		t.Fatalf("SetAuto(bar) error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("size func calls = %d, want 1", calls)
+	}
+	if want := encodedSize(foo) + 3 + 1000; c.Size() != want {
+		t.Errorf("size = %d, want %d", c.Size(), want)
+	}
+
+	c.SetSizeFunc(nil)
+	if err := c.SetAuto("bar", bar, 60); err != nil {
+		t.Fatalf("SetAuto(bar) error = %v", err)
+	}
+	if want := encodedSize(foo) + encodedSize(bar); c.Size() != want {
+		t.Errorf("size = %d, want %d", c.Size(), want)
+	}
+
+	// not gob-encodable
+	fc := New[string, func()](0)
+	if err := fc.SetAuto("f", func() {}, 60); err == nil {
+		t.Errorf("SetAuto() of a func value should fail")
+	}
+
+	c.SetReadOnly(true)
+	if err := c.SetAuto("baz", foo, 60); err != ErrReadOnly {
+		t.Errorf("read-only SetAuto() error = %v, want %v", err, ErrReadOnly)
+	}
+}
@@ -0,0 +1,48 @@
+package expirecache
+
+import "time"
+
+// slowOpWatcher reports the operations holding the write lock for at least threshold
+type slowOpWatcher struct {
+	threshold time.Duration
+	f         func(op string, d time.Duration)
+}
+
+// SetOnSlowOp sets a callback invoked when an operation holds the write lock for at least threshold,
+// with the operation name ("set", "apply", "cleaner" for a Cleaner batch, "approximate cleaner" for
+// an ApproximateCleaner iteration) and the lock hold duration. The callback is invoked with no lock held,
+// in the goroutine of the operation. The lock hold isn't measured unless a callback is set, pass a nil f to disable.
+func (ec *Cache[K, T]) SetOnSlowOp(threshold time.Duration, f func(op string, d time.Duration)) {
+	if f == nil {
+		ec.slowOps.Store((*slowOpWatcher)(nil))
+		return
+	}
+	ec.slowOps.Store(&slowOpWatcher{threshold: threshold, f: f})
+}
+
+// lockHeld returns the time the write lock is acquired at, zero if the lock hold isn't measured
+func (ec *Cache[K, T]) lockHeld() time.Time {
+	if w, _ := ec.slowOps.Load().(*slowOpWatcher); w == nil {
+		return time.Time{}
+	}
+	return timeNow()
+}
+
+// holdTime returns the time the write lock acquired at held is held for, called just before releasing it.
+// It's -1 if the lock hold isn't measured.
+func (ec *Cache[K, T]) holdTime(held time.Time) time.Duration {
+	if held.IsZero() {
+		return -1
+	}
+	return timeNow().Sub(held)
+}
+
+// slowOp reports the operation if it held the write lock for at least the threshold, called with no lock held
+func (ec *Cache[K, T]) slowOp(op string, hold time.Duration) {
+	if hold < 0 {
+		return
+	}
+	if w, _ := ec.slowOps.Load().(*slowOpWatcher); w != nil && hold >= w.threshold {
+		w.f(op, hold)
+	}
+}
@@ -0,0 +1,60 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheOnSlowOp(t *testing.T) {
+	c := New[int, int](1)
+
+	type slowOp struct {
+		op string
+		d  time.Duration
+	}
+	var ops []slowOp
+	c.SetOnSlowOp(50*time.Millisecond, func(op string, d time.Duration) {
+		ops = append(ops, slowOp{op, d})
+	})
+
+	c.Set(1, 1, 1, 60)
+	// a slow OnSpill callback is invoked with no lock held
+	c.SetOnSpill(func(k, v int) { time.Sleep(60 * time.Millisecond) }, false)
+	c.Set(2, 2, 1, 60)
+	if len(ops) != 0 {
+		t.Errorf("slow ops = %v, want none", ops)
+	}
+
+	// a slow callback under the lock
+	c.SetVetoEvict(func(k, v int) bool {
+		time.Sleep(60 * time.Millisecond)
+		return false
+	})
+	c.Set(3, 3, 1, 60)
+	if len(ops) != 1 || ops[0].op != "set" || ops[0].d < 60*time.Millisecond {
+		t.Errorf("slow ops = %v, want a set of at least 60ms", ops)
+	}
+
+	// a slow Cleaner batch
+	defer func() {
+		timeNow = time.Now
+	}()
+	t0 := time.Now()
+	var calls int
+	timeNow = func() time.Time {
+		calls++
+		return t0.Add(time.Duration(calls) * time.Second)
+	}
+	ops = nil
+	c.cleanAll(t0)
+	if len(ops) != 1 || ops[0].op != "cleaner" || ops[0].d != time.Second {
+		t.Errorf("slow ops = %v, want a cleaner of 1s", ops)
+	}
+
+	c.SetOnSlowOp(0, nil)
+	ops = nil
+	c.cleanAll(t0)
+	if len(ops) != 0 {
+		t.Errorf("slow ops = %v after disabling, want none", ops)
+	}
+}
@@ -0,0 +1,282 @@
+package expirecache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotBatchSize is the maximum number of items copied under a single read lock hold by SaveToWriter
+const snapshotBatchSize = 256
+
+// maxRecordSize is the maximum length of a snapshot record accepted by LoadFromReader
+const maxRecordSize = 1 << 30
+
+// ErrRecordTooLarge is returned by LoadFromReader for a record longer than the accepted maximum
+var ErrRecordTooLarge = errors.New("expirecache: snapshot record too large")
+
+type snapshotRecord[K comparable, T any] struct {
+	Key        K
+	Value      T
+	Size       uint64
+	ValidUntil time.Time
+}
+
+// snapshotFormatRecord is a record of an item in a per-item format, preceded by a zero length in the stream.
+// None of its fields match the snapshotRecord ones, so it's never decoded as one.
+type snapshotFormatRecord[K comparable] struct {
+	Format string
+	Data   []byte // the value encoded by the codec of Format
+	Item   snapshotItem[K]
+}
+
+type snapshotItem[K comparable] struct {
+	Key        K
+	Size       uint64
+	ValidUntil time.Time
+}
+
+// SnapshotCodec encodes and decodes the values of a per-item snapshot format, see SetSnapshotCodecs
+type SnapshotCodec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// SetSnapshotCodecs sets the per-item snapshot formats, e.g. for the values of different concrete types behind
+// an interface T: SaveToWriter records each value with the format returned by format, encoded by the codec of
+// the format, and LoadFromReader decodes it with the codec of the recorded format. Values of the empty format are
+// gob-encoded as without the formats. format and the codecs are invoked with no lock held.
+// Pass a nil format to gob-encode all values.
+func (ec *Cache[K, T]) SetSnapshotCodecs(format func(v T) string, codecs map[string]SnapshotCodec[T]) {
+	ec.Lock()
+	if format == nil {
+		codecs = nil
+	}
+	ec.snapshotFormat = format
+	ec.snapshotCodecs = codecs
+	ec.Unlock()
+}
+
+// SaveToWriter writes the unexpired items to w as a stream of length-prefixed gob records
+// of a single gob encoder (the type information is sent once, with the first record of the type),
+// with the values in the per-item formats if set (see SetSnapshotCodecs).
+// Keys are snapshotted on start and the items are copied in small batches, releasing the read lock in between,
+// so only the keys are copied for the whole cache and writers aren't blocked by a slow w.
+// Items added during the save aren't written, items removed or expired meanwhile are skipped,
+// the changed ones are written with the value at the time of their batch.
+func (ec *Cache[K, T]) SaveToWriter(w io.Writer) error {
+	var (
+		buf    bytes.Buffer
+		lenBuf [binary.MaxVarintLen64]byte
+		batch  = make([]snapshotRecord[K, T], 0, snapshotBatchSize)
+	)
+	ec.RLock()
+	format, codecs := ec.snapshotFormat, ec.snapshotCodecs
+	ec.RUnlock()
+	enc := gob.NewEncoder(&buf)
+	write := func(rec any) error {
+		buf.Reset()
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		l := binary.PutUvarint(lenBuf[:], uint64(buf.Len()))
+		if _, err := w.Write(lenBuf[:l]); err != nil {
+			return err
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	// not by the index in ec.keys, removals move the last key into a freed slot already visited
+	keys := ec.snapshotKeys(false)
+	for len(keys) > 0 {
+		end := snapshotBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch = batch[:0]
+		now := timeNow()
+		ec.RLock()
+		for _, k := range keys[:end] {
+			if v, ok := ec.get(k); ok && ec.alive(k, v, now) {
+				batch = append(batch, snapshotRecord[K, T]{Key: k, Value: v.data, Size: v.size, ValidUntil: v.validUntil})
+			}
+		}
+		ec.RUnlock()
+		keys = keys[end:]
+
+		for n := range batch {
+			rec := &batch[n]
+			var f string
+			if format != nil {
+				f = format(rec.Value)
+			}
+			if f == "" {
+				if err := write(rec); err != nil {
+					return fmt.Errorf("expirecache: encode %v: %w", rec.Key, err)
+				}
+				continue
+			}
+			codec, ok := codecs[f]
+			if !ok {
+				return fmt.Errorf("expirecache: encode %v: no codec for the format %q", rec.Key, f)
+			}
+			data, err := codec.Encode(rec.Value)
+			if err != nil {
+				return fmt.Errorf("expirecache: encode %v: %w", rec.Key, err)
+			}
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+			if err := write(&snapshotFormatRecord[K]{Format: f, Data: data, Item: snapshotItem[K]{Key: rec.Key, Size: rec.Size, ValidUntil: rec.ValidUntil}}); err != nil {
+				return fmt.Errorf("expirecache: encode %v: %w", rec.Key, err)
+			}
+		}
+		// don't hold references to the values
+		var zero snapshotRecord[K, T]
+		for n := range batch {
+			batch[n] = zero
+		}
+	}
+	return nil
+}
+
+// LoadFromReader reads items written by SaveToWriter from r one record at a time and stores them in the cache
+// with the remaining time to live. Items expired since the save are skipped.
+// Records which can't be decoded (corrupt, written for another value type, or in a per-item format without a codec,
+// see SetSnapshotCodecs) are skipped too and counted in skipped,
+// an error is returned only if the stream itself is broken, e.g. truncated.
+// A corrupt record carrying the type information makes the following records of the type undecodable.
+// Concatenated snapshots are read as well.
+func (ec *Cache[K, T]) LoadFromReader(r io.Reader) (skipped int, err error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(r)
+		br = b
+		r = b
+	}
+	ec.RLock()
+	codecs := ec.snapshotCodecs
+	ec.RUnlock()
+	var (
+		buf []byte
+		fr  frameReader
+	)
+	// the records share a decoder, as they are written by a single encoder
+	dec := gob.NewDecoder(&fr)
+	decode := func(d *gob.Decoder, formatted bool, rec *snapshotRecord[K, T]) error {
+		fr.reset(buf)
+		if formatted {
+			return decodeFormatRecord(d, codecs, rec)
+		}
+		return d.Decode(rec)
+	}
+	for {
+		l, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return skipped, nil
+		} else if err != nil {
+			return skipped, err
+		}
+		// a zero length precedes a record in a per-item format
+		formatted := l == 0
+		if formatted {
+			if l, err = binary.ReadUvarint(br); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return skipped, err
+			}
+		}
+		if l > maxRecordSize {
+			return skipped, ErrRecordTooLarge
+		}
+		if uint64(cap(buf)) < l {
+			buf = make([]byte, l)
+		}
+		buf = buf[:l]
+		if _, err = io.ReadFull(r, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return skipped, err
+		}
+		var rec snapshotRecord[K, T]
+		if err = decode(dec, formatted, &rec); err != nil {
+			// the type information sent again, by the encoder of a concatenated snapshot
+			// (or of an old snapshot, written with an encoder per record), needs a new decoder
+			rec = snapshotRecord[K, T]{}
+			d := gob.NewDecoder(&fr)
+			if err = decode(d, formatted, &rec); err != nil {
+				// records are length-prefixed, so the next one can still be read
+				skipped++
+				continue
+			}
+			dec = d
+		}
+		ttl := rec.ValidUntil.Sub(timeNow())
+		if ttl < 0 {
+			continue
+		}
+		ec.Lock()
+		if ec.readOnly {
+			err := ec.errReadOnly()
+			ec.Unlock()
+			return skipped, err
+		}
+		ec.actualSet(rec.Key, rec.Value, rec.Size, ttl)
+		ec.unlock()
+	}
+}
+
+// decodeFormatRecord decodes a record in a per-item format with the codec of the format
+func decodeFormatRecord[K comparable, T any](dec *gob.Decoder, codecs map[string]SnapshotCodec[T], rec *snapshotRecord[K, T]) error {
+	var fr snapshotFormatRecord[K]
+	if err := dec.Decode(&fr); err != nil {
+		return err
+	}
+	codec, ok := codecs[fr.Format]
+	if !ok {
+		return fmt.Errorf("expirecache: no codec for the format %q", fr.Format)
+	}
+	v, err := codec.Decode(fr.Data)
+	if err != nil {
+		return err
+	}
+	*rec = snapshotRecord[K, T]{Key: fr.Item.Key, Value: v, Size: fr.Item.Size, ValidUntil: fr.Item.ValidUntil}
+	return nil
+}
+
+// frameReader reads a single record for the shared decoder of LoadFromReader.
+// It's an io.ByteReader, so the decoder doesn't buffer it and never reads past the record.
+type frameReader struct {
+	buf []byte
+	off int
+}
+
+func (r *frameReader) reset(buf []byte) {
+	r.buf = buf
+	r.off = 0
+}
+
+func (r *frameReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.buf) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf[r.off:])
+	r.off += n
+	return n, nil
+}
+
+func (r *frameReader) ReadByte() (byte, error) {
+	if r.off >= len(r.buf) {
+		return 0, io.EOF
+	}
+	b := r.buf[r.off]
+	r.off++
+	return b, nil
+}
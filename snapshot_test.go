@@ -0,0 +1,315 @@
+package expirecache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type maxWriter struct {
+	bytes.Buffer
+	writes   int
+	maxWrite int
+}
+
+func (w *maxWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if len(p) > w.maxWrite {
+		w.maxWrite = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+type snapshotValue struct {
+	Name  string
+	Count int
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	c := New[string, snapshotValue](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	const items = 5000
+	for i := 0; i < items; i++ {
+		c.Set(fmt.Sprintf("key%d", i), snapshotValue{Name: fmt.Sprintf("value%d", i), Count: i}, uint64(i%100), int32(60+i%60))
+	}
+	// expired before the save
+	c.Set("expired", snapshotValue{Name: "expired"}, 1, 0)
+	timeNow = func() time.Time { return t0.Add(time.Second) }
+
+	var w maxWriter
+	if err := c.SaveToWriter(&w); err != nil {
+		t.Fatalf("SaveToWriter() error = %v", err)
+	}
+	// records are streamed one by one, not buffered for the whole cache
+	if w.writes != 2*items {
+		t.Errorf("SaveToWriter() writes = %d, want %d", w.writes, 2*items)
+	}
+	if w.maxWrite > 1024 {
+		t.Errorf("SaveToWriter() max write = %d, want a single record size (total %d)", w.maxWrite, w.Len())
+	}
+
+	// load with the clock moved by 30 seconds
+	timeNow = func() time.Time { return t0.Add(31 * time.Second) }
+	loaded := New[string, snapshotValue](0)
+	data := w.Bytes()
+	allocs := testing.AllocsPerRun(1, func() {
+		loaded.ClearAll()
+		if skipped, err := loaded.LoadFromReader(bytes.NewReader(data)); err != nil || skipped != 0 {
+			t.Fatalf("LoadFromReader() = (%d, %v), want (0, nil)", skipped, err)
+		}
+	})
+	// the records share a gob decoder, so only the values are allocated per record, nothing for the whole snapshot
+	if perRecord := allocs / items; perRecord > 20 {
+		t.Errorf("LoadFromReader() allocations per record = %v", perRecord)
+	}
+
+	if loaded.Items() != items {
+		t.Fatalf("loaded items = %d, want %d", loaded.Items(), items)
+	}
+	// the expired item is still in the source cache
+	if loaded.Size() != c.Size()-1 {
+		t.Errorf("loaded size = %d, want %d", loaded.Size(), c.Size()-1)
+	}
+	for i := 0; i < items; i += 997 {
+		k := fmt.Sprintf("key%d", i)
+		info, ok := loaded.Inspect(k)
+		if !ok {
+			t.Errorf("loaded %s should be present", k)
+			continue
+		}
+		if want := (snapshotValue{Name: fmt.Sprintf("value%d", i), Count: i}); info.Value != want {
+			t.Errorf("loaded %s = %+v, want %+v", k, info.Value, want)
+		}
+		if want := time.Duration(60+i%60)*time.Second - 31*time.Second; info.TTL != want {
+			t.Errorf("loaded %s TTL = %v, want %v", k, info.TTL, want)
+		}
+	}
+	if _, ok := loaded.Get("expired"); ok {
+		t.Errorf("expired item should not be saved")
+	}
+
+	// truncated snapshot
+	loaded.ClearAll()
+	if _, err := loaded.LoadFromReader(bytes.NewReader(data[:len(data)-3])); err == nil {
+		t.Errorf("LoadFromReader() of the truncated snapshot should fail")
+	}
+}
+
+// deleteWriter deletes a key from the cache on the first write
+type deleteWriter struct {
+	bytes.Buffer
+	c   *Cache[int, int]
+	key int
+}
+
+func (w *deleteWriter) Write(p []byte) (int, error) {
+	if w.c != nil {
+		w.c.Delete(w.key)
+		w.c = nil
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestCacheSnapshotDeleteDuringSave(t *testing.T) {
+	c := New[int, int](0)
+	const items = 3 * snapshotBatchSize
+	for i := 0; i < items; i++ {
+		c.Set(i, i, 1, 60)
+	}
+	// removing the item moves the last key into its slot, already visited by the first batch
+	w := deleteWriter{c: c, key: 0}
+	if err := c.SaveToWriter(&w); err != nil {
+		t.Fatalf("SaveToWriter() error = %v", err)
+	}
+
+	loaded := New[int, int](0)
+	if skipped, err := loaded.LoadFromReader(&w); err != nil || skipped != 0 {
+		t.Fatalf("LoadFromReader() = (%d, %v), want (0, nil)", skipped, err)
+	}
+	for i := 0; i < items; i++ {
+		if v, ok := loaded.Get(i); !ok || v != i {
+			t.Errorf("loaded.Get(%d) = (%d, %v), want (%d, true)", i, v, ok, i)
+		}
+	}
+}
+
+func TestCacheSnapshotCorruptRecord(t *testing.T) {
+	var buf bytes.Buffer
+	for i, k := range []string{"foo", "bar"} {
+		c := New[string, snapshotValue](0)
+		c.Set(k, snapshotValue{Name: k, Count: i}, 3, 60)
+		if err := c.SaveToWriter(&buf); err != nil {
+			t.Fatalf("SaveToWriter() error = %v", err)
+		}
+		if i == 0 {
+			// a corrupt record between the valid ones
+			buf.Write([]byte{4, 0xde, 0xad, 0xbe, 0xef})
+		}
+	}
+	// a record of another value type
+	other := New[string, int](0)
+	other.Set("baz", 1, 3, 60)
+	if err := other.SaveToWriter(&buf); err != nil {
+		t.Fatalf("SaveToWriter() error = %v", err)
+	}
+
+	loaded := New[string, snapshotValue](0)
+	skipped, err := loaded.LoadFromReader(&buf)
+	if err != nil || skipped != 2 {
+		t.Fatalf("LoadFromReader() = (%d, %v), want (2, nil)", skipped, err)
+	}
+	for i, k := range []string{"foo", "bar"} {
+		if v, ok := loaded.Get(k); !ok || v != (snapshotValue{Name: k, Count: i}) {
+			t.Errorf("loaded.Get(%s) = (%+v, %v), want it loaded", k, v, ok)
+		}
+	}
+	if loaded.Items() != 2 {
+		t.Errorf("loaded items = %d, want 2", loaded.Items())
+	}
+}
+
+func TestCacheSnapshotEncoderPerRecord(t *testing.T) {
+	// a snapshot written with a new gob encoder for each record
+	var (
+		buf    bytes.Buffer
+		lenBuf [binary.MaxVarintLen64]byte
+	)
+	validUntil := time.Now().Add(time.Minute)
+	for i, k := range []string{"foo", "bar", "baz"} {
+		var rec bytes.Buffer
+		if err := gob.NewEncoder(&rec).Encode(&snapshotRecord[string, snapshotValue]{Key: k, Value: snapshotValue{Name: k, Count: i}, Size: 3, ValidUntil: validUntil}); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		buf.Write(lenBuf[:binary.PutUvarint(lenBuf[:], uint64(rec.Len()))])
+		buf.Write(rec.Bytes())
+	}
+
+	loaded := New[string, snapshotValue](0)
+	if skipped, err := loaded.LoadFromReader(&buf); err != nil || skipped != 0 {
+		t.Fatalf("LoadFromReader() = (%d, %v), want (0, nil)", skipped, err)
+	}
+	for i, k := range []string{"foo", "bar", "baz"} {
+		if v, ok := loaded.Get(k); !ok || v != (snapshotValue{Name: k, Count: i}) {
+			t.Errorf("loaded.Get(%s) = (%+v, %v), want it loaded", k, v, ok)
+		}
+	}
+}
+
+type shape interface {
+	Area() float64
+}
+
+type circle struct{ R float64 }
+
+func (c circle) Area() float64 { return 3 * c.R * c.R }
+
+type square struct{ Side int }
+
+func (s square) Area() float64 { return float64(s.Side * s.Side) }
+
+type circleCodec struct{}
+
+func (circleCodec) Encode(v shape) ([]byte, error) { return json.Marshal(v.(circle)) }
+
+func (circleCodec) Decode(data []byte) (shape, error) {
+	var c circle
+	err := json.Unmarshal(data, &c)
+	return c, err
+}
+
+type squareCodec struct{}
+
+func (squareCodec) Encode(v shape) ([]byte, error) {
+	return strconv.AppendInt(nil, int64(v.(square).Side), 10), nil
+}
+
+func (squareCodec) Decode(data []byte) (shape, error) {
+	side, err := strconv.Atoi(string(data))
+	return square{Side: side}, err
+}
+
+func TestCacheSnapshotCodecs(t *testing.T) {
+	format := func(v shape) string {
+		switch v.(type) {
+		case circle:
+			return "circle"
+		case square:
+			return "square"
+		}
+		return "unknown"
+	}
+	codecs := map[string]SnapshotCodec[shape]{"circle": circleCodec{}, "square": squareCodec{}}
+
+	c := New[int, shape](0)
+	c.SetSnapshotCodecs(format, codecs)
+	if cfg := c.Config(); cfg.SnapshotCodecs != 2 {
+		t.Errorf("cache.Config().SnapshotCodecs = %d, want 2", cfg.SnapshotCodecs)
+	}
+	const items = 100
+	for i := 0; i < items; i++ {
+		if i%2 == 0 {
+			c.Set(i, circle{R: float64(i) / 2}, uint64(i), 60)
+		} else {
+			c.Set(i, square{Side: i}, uint64(i), 60)
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.SaveToWriter(&buf); err != nil {
+		t.Fatalf("SaveToWriter() error = %v", err)
+	}
+	data := buf.Bytes()
+
+	loaded := New[int, shape](0)
+	loaded.SetSnapshotCodecs(format, codecs)
+	if skipped, err := loaded.LoadFromReader(bytes.NewReader(data)); err != nil || skipped != 0 {
+		t.Fatalf("LoadFromReader() = (%d, %v), want (0, nil)", skipped, err)
+	}
+	if loaded.Items() != items || loaded.Size() != c.Size() {
+		t.Errorf("loaded items = %d (size %d), want %d (size %d)", loaded.Items(), loaded.Size(), items, c.Size())
+	}
+	for i := 0; i < items; i++ {
+		var want shape = square{Side: i}
+		if i%2 == 0 {
+			want = circle{R: float64(i) / 2}
+		}
+		if v, ok := loaded.Get(i); !ok || v != want {
+			t.Errorf("loaded.Get(%d) = (%#v, %v), want (%#v, true)", i, v, ok, want)
+		}
+	}
+
+	// the records of a format without a codec are skipped
+	partial := New[int, shape](0)
+	partial.SetSnapshotCodecs(format, map[string]SnapshotCodec[shape]{"square": squareCodec{}})
+	if skipped, err := partial.LoadFromReader(bytes.NewReader(data)); err != nil || skipped != items/2 {
+		t.Errorf("LoadFromReader() without the circle codec = (%d, %v), want (%d, nil)", skipped, err, items/2)
+	}
+	if partial.Items() != items/2 {
+		t.Errorf("partially loaded items = %d, want %d", partial.Items(), items/2)
+	}
+
+	// truncated after the format marker of the first record
+	if data[0] != 0 {
+		t.Fatalf("snapshot starts with %d, want the format marker", data[0])
+	}
+	if _, err := loaded.LoadFromReader(bytes.NewReader(data[:1])); err != io.ErrUnexpectedEOF {
+		t.Errorf("LoadFromReader() of the truncated snapshot error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	c.Set(-1, nil, 1, 60)
+	if err := c.SaveToWriter(io.Discard); err == nil {
+		t.Errorf("SaveToWriter() of a format without a codec should fail")
+	}
+}
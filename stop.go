@@ -0,0 +1,57 @@
+package expirecache
+
+import "errors"
+
+// ErrClosed is returned by operations with an error result when the cache is closed, see CloseOnStop
+var ErrClosed = errors.New("expirecache: cache is closed")
+
+// Option configures a cache created with New, NewWithStore or NewCopyOnWrite
+type Option func(*options)
+
+type options struct {
+	closeOnStop bool
+	setRate     float64
+	setBurst    int
+	preload     func(ec any)
+	maxEntries  int
+	twoQueue    bool
+}
+
+// CloseOnStop makes Stop close the cache: all items are dropped and all operations are rejected,
+// like in the read-only mode (see SetReadOnly) with nothing to read. Get and the other reads miss,
+// operations with an error result (SetAuto, SetCtx, SetTier, LoadFromReader, ComputeIfAbsent, GetOrLoad,
+// GetManyReadThrough) return ErrClosed.
+func CloseOnStop() Option {
+	return func(o *options) {
+		o.closeOnStop = true
+	}
+}
+
+// Stop stops the cleaners: their goroutines exit after waking up, and newly started ones exit on the first wake up.
+// The cache keeps serving reads and writes, unless it was created with CloseOnStop.
+func (ec *Cache[K, T]) Stop() {
+	ec.Lock()
+	ec.stopped = true
+	if ec.closeOnStop && !ec.closed {
+		ec.clearAll()
+		ec.closed = true
+		ec.readOnly = true
+	}
+	ec.Unlock()
+}
+
+// Stopped checks if Stop was called
+func (ec *Cache[K, T]) Stopped() bool {
+	ec.RLock()
+	stopped := ec.stopped
+	ec.RUnlock()
+	return stopped
+}
+
+// errReadOnly returns the error of a rejected mutation, must be called under the lock
+func (ec *Cache[K, T]) errReadOnly() error {
+	if ec.closed {
+		return ErrClosed
+	}
+	return ErrReadOnly
+}
@@ -0,0 +1,90 @@
+package expirecache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheStop(t *testing.T) {
+	sleep := make(chan bool)
+	cleanerSleep = mockCleanerSleep(sleep)
+
+	defer func() {
+		cleanerSleep = time.Sleep
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c := New[string, string](0)
+	c.Set("foo", "bar", 3, 60)
+	c.Set("old", "bar", 3, 1)
+
+	exited := make(chan struct{})
+	go func() {
+		c.Cleaner(time.Minute)
+		close(exited)
+	}()
+	c.Stop()
+	if !c.Stopped() {
+		t.Errorf("cache.Stopped() = false after Stop")
+	}
+	timeNow = func() time.Time { return t0.Add(time.Minute) }
+	sleep <- true
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatalf("the cleaner is running after Stop")
+	}
+	if c.Items() != 2 {
+		t.Errorf("items = %d, want the expired item kept", c.Items())
+	}
+
+	// reads and writes are served
+	if v, ok := c.Get("foo"); !ok || v != "bar" {
+		t.Errorf("cache.Get(foo) after Stop = (%q, %v), want (%q, true)", v, ok, "bar")
+	}
+	c.Set("baz", "qux", 3, 60)
+	if v, ok := c.Get("baz"); !ok || v != "qux" {
+		t.Errorf("cache.Get(baz) after Stop = (%q, %v), want (%q, true)", v, ok, "qux")
+	}
+	if err := c.SetAuto("zot", "bork", 60); err != nil {
+		t.Errorf("cache.SetAuto(zot) after Stop error = %v", err)
+	}
+}
+
+func TestCacheCloseOnStop(t *testing.T) {
+	for _, c := range []*Cache[string, string]{New[string, string](0, CloseOnStop()), NewCopyOnWrite[string, string](0, CloseOnStop())} {
+		c.Set("foo", "bar", 3, 60)
+		c.Stop()
+		c.Stop()
+
+		if _, ok := c.Get("foo"); ok {
+			t.Errorf("cache.Get(foo) of the closed cache should miss")
+		}
+		c.Set("baz", "qux", 3, 60)
+		if _, ok := c.Get("baz"); ok || c.Items() != 0 || c.Size() != 0 {
+			t.Errorf("cache.Get(baz) of the closed cache = %v, items %d, size %d, want false, 0, 0", ok, c.Items(), c.Size())
+		}
+		if err := c.SetAuto("baz", "qux", 60); !errors.Is(err, ErrClosed) {
+			t.Errorf("cache.SetAuto(baz) error = %v, want %v", err, ErrClosed)
+		}
+		if err := c.SetTier("baz", "qux", 3, "short"); !errors.Is(err, ErrClosed) {
+			t.Errorf("cache.SetTier(baz) error = %v, want %v", err, ErrClosed)
+		}
+		if _, _, err := c.ComputeIfAbsent("baz", 3, 60, func() (string, error) { return "qux", nil }); !errors.Is(err, ErrClosed) {
+			t.Errorf("cache.ComputeIfAbsent(baz) error = %v, want %v", err, ErrClosed)
+		}
+		// stays closed
+		c.SetReadOnly(false)
+		c.Set("baz", "qux", 3, 60)
+		if c.Items() != 0 {
+			t.Errorf("items = %d after SetReadOnly(false), want 0", c.Items())
+		}
+		if cfg := c.Config(); !cfg.Stopped || !cfg.CloseOnStop || !cfg.ReadOnly {
+			t.Errorf("cache.Config() = %+v, want stopped, closed and read-only", cfg)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package expirecache
+
+// Entry is an item kept in a Store, it's opaque outside of the package
+type Entry[T any] element[T]
+
+// Store is the backing map of the cache keys to the items, see NewWithStore.
+// The cache calls Set and Delete under its write lock, Get may be called concurrently under its read lock.
+// The cache keeps its own list of keys, so a Store doesn't need to support iteration.
+type Store[K comparable, T any] interface {
+	Get(k K) (e *Entry[T], ok bool)
+	Set(k K, e *Entry[T])
+	Delete(k K)
+}
+
+// mapStore is the default Store, a builtin map
+type mapStore[K comparable, T any] map[K]*Entry[T]
+
+func (m mapStore[K, T]) Get(k K) (*Entry[T], bool) {
+	e, ok := m[k]
+	return e, ok
+}
+
+func (m mapStore[K, T]) Set(k K, e *Entry[T]) { m[k] = e }
+
+func (m mapStore[K, T]) Delete(k K) { delete(m, k) }
+
+// get returns the item for the key from the store
+func (ec *Cache[K, T]) get(k K) (*element[T], bool) {
+	e, ok := ec.cache.Get(k)
+	return (*element[T])(e), ok
+}
+
+// elem returns the item for the key from the store, nil if it's absent
+func (ec *Cache[K, T]) elem(k K) *element[T] {
+	e, _ := ec.cache.Get(k)
+	return (*element[T])(e)
+}
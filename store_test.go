@@ -0,0 +1,65 @@
+package expirecache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingStore is a Store counting the calls, backed by a sync.Map
+type countingStore[K comparable, T any] struct {
+	gets       int64 // Get may be called concurrently
+	sets, dels int64
+	m          sync.Map
+}
+
+func (s *countingStore[K, T]) Get(k K) (*Entry[T], bool) {
+	atomic.AddInt64(&s.gets, 1)
+	e, ok := s.m.Load(k)
+	if !ok {
+		return nil, false
+	}
+	return e.(*Entry[T]), true
+}
+
+func (s *countingStore[K, T]) Set(k K, e *Entry[T]) {
+	s.sets++
+	s.m.Store(k, e)
+}
+
+func (s *countingStore[K, T]) Delete(k K) {
+	s.dels++
+	s.m.Delete(k)
+}
+
+func TestCacheWithStore(t *testing.T) {
+	s := &countingStore[string, string]{}
+	c := NewWithStore[string, string](10, s)
+
+	c.Set("foo", "bar", 3, 60, WithPriority(PriorityLow))
+	c.Set("baz", "qux", 3, 60)
+	if v, ok := c.Get("foo"); !ok || v != "bar" {
+		t.Errorf("cache.Get(foo) = (%q, %v), want (%q, true)", v, ok, "bar")
+	}
+	if _, ok := c.Get("missing"); ok {
+		t.Errorf("cache.Get(missing) should miss")
+	}
+	if !c.Delete("baz") {
+		t.Errorf("cache.Delete(baz) = false, want true")
+	}
+	if _, ok := s.m.Load("baz"); ok {
+		t.Errorf("deleted item is still in the store")
+	}
+	// evicts foo
+	c.Set("big", "big", 10, 60)
+	if _, ok := c.Get("foo"); ok {
+		t.Errorf("cache.Get(foo) should miss after the eviction")
+	}
+	c.ClearAll()
+	if _, ok := s.m.Load("big"); ok {
+		t.Errorf("cleared item is still in the store")
+	}
+	if s.gets == 0 || s.sets != 3 || s.dels != 3 {
+		t.Errorf("store gets, sets, deletes = %d, %d, %d, want >0, 3, 3", s.gets, s.sets, s.dels)
+	}
+}
@@ -0,0 +1,108 @@
+package expirecache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// streamedItem is an item copied by StreamTo
+type streamedItem[K comparable, T any] struct {
+	k    K
+	v    T
+	size uint64
+	ttl  time.Duration
+	opts []SetOption
+}
+
+// StreamTo copies the unexpired items to dst in batches of batchSize (at least 1) for a live migration.
+// Each batch is read under the read lock and written under the dst lock, so neither cache is locked for long.
+// The size, remaining time to live, stickiness, priority, savings and tags are preserved, the dependencies aren't.
+// It's best-effort: the items set in the cache while streaming may be copied with the new or the old value, or not at all,
+// the items deleted may still be copied. Nothing is copied if dst is read-only.
+func (ec *Cache[K, T]) StreamTo(dst *Cache[K, T], batchSize int) {
+	if dst == ec {
+		return
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	keys := ec.snapshotKeys(true)
+	batch := make([]streamedItem[K, T], 0, batchSize)
+	for len(keys) > 0 {
+		n := batchSize
+		if n > len(keys) {
+			n = len(keys)
+		}
+
+		now := timeNow()
+		batch = batch[:0]
+		ec.RLock()
+		for _, k := range keys[:n] {
+			v, ok := ec.get(k)
+			if !ok || !ec.alive(k, v, now) {
+				continue
+			}
+			opts := []SetOption{WithPriority(v.priority), WithSavings(v.savings), WithSavedLatency(v.latency), WithTags(v.tags...)}
+			if v.sticky {
+				opts = append(opts, Sticky())
+			}
+			batch = append(batch, streamedItem[K, T]{
+				k:    k,
+				v:    v.data,
+				size: v.size,
+				ttl:  v.validUntil.Sub(now),
+				opts: opts,
+			})
+		}
+		ec.RUnlock()
+		keys = keys[n:]
+
+		dst.Lock()
+		if dst.readOnly {
+			dst.Unlock()
+			return
+		}
+		for _, it := range batch {
+			dst.actualSet(it.k, it.v, it.size, it.ttl, it.opts...)
+		}
+		dst.unlock()
+	}
+}
+
+// Stream returns a channel yielding the unexpired items with their metadata, for pipelined processing without
+// copying the whole cache. Keys are snapshotted on start, the items are read lazily as the channel is consumed,
+// so items added meanwhile aren't yielded and items removed or expired meanwhile are skipped.
+// The channel is closed after the last item, or when ctx is done: the consumer must either drain the channel
+// or cancel ctx, so the producing goroutine exits.
+func (ec *Cache[K, T]) Stream(ctx context.Context) <-chan EntrySnapshot[K, T] {
+	keys := ec.snapshotKeys(false)
+	ch := make(chan EntrySnapshot[K, T])
+	go func() {
+		defer close(ch)
+		for _, k := range keys {
+			now := timeNow()
+			ec.RLock()
+			v, ok := ec.get(k)
+			if !ok || !ec.alive(k, v, now) {
+				ec.RUnlock()
+				continue
+			}
+			e := EntrySnapshot[K, T]{
+				Key:     k,
+				Value:   v.data,
+				Size:    v.size,
+				Expires: v.validUntil,
+				Created: v.created,
+				Hits:    atomic.LoadUint64(&v.hits),
+			}
+			ec.RUnlock()
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,117 @@
+package expirecache
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheStreamTo(t *testing.T) {
+	src := New[int, int](0)
+	dst := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	const items = 1000
+	for i := 0; i < items; i++ {
+		src.Set(i, i, 1, int32(60+i%60))
+	}
+	src.Set(-1, -1, 1, -1) // expired
+
+	// concurrent overwrites and deletes of the source while streaming
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < items; i += 2 {
+			src.Set(i, items+i, 2, 600)
+			src.Delete(i + 1)
+		}
+	}()
+	src.StreamTo(dst, 64)
+	wg.Wait()
+
+	if _, ok := dst.Inspect(-1); ok {
+		t.Errorf("dst.Inspect(-1) of the expired item should miss")
+	}
+	// the odd items deleted before being read aren't copied
+	if n := dst.Items(); n < items/2 || n > items {
+		t.Fatalf("dst items = %d, want in [%d, %d]", n, items/2, items)
+	}
+	for i := 0; i < items; i++ {
+		info, ok := dst.Inspect(i)
+		if !ok {
+			if i%2 == 0 {
+				t.Errorf("dst.Inspect(%d) should be present", i)
+			}
+			continue
+		}
+		// the old or the new value, consistent with its size and TTL
+		switch {
+		case info.Value == i && info.Size == 1 && info.TTL == time.Duration(60+i%60)*time.Second:
+		case i%2 == 0 && info.Value == items+i && info.Size == 2 && info.TTL == 10*time.Minute:
+		default:
+			t.Errorf("dst.Inspect(%d) = %+v, want a source value", i, info)
+		}
+	}
+	if size := dst.Size(); size < items/2 || size > items+items/2 {
+		t.Errorf("dst size = %d, want in [%d, %d]", size, items/2, items+items/2)
+	}
+
+	// streamed again when the source is quiet, the destination gets the latest values
+	src.StreamTo(dst, 0)
+	for i := 0; i < items; i += 2 {
+		if v, ok := dst.Get(i); !ok || v != items+i {
+			t.Errorf("dst.Get(%d) = (%d, %v), want (%d, true)", i, v, ok, items+i)
+		}
+	}
+}
+
+func TestCacheStream(t *testing.T) {
+	c := New[int, int](0)
+	const items = 100
+	for i := 0; i < items; i++ {
+		c.Set(i, i*2, 1, 60)
+	}
+	c.Set(-1, -1, 1, -1) // expired
+
+	seen := make(map[int]bool)
+	for e := range c.Stream(context.Background()) {
+		if e.Value != e.Key*2 || e.Size != 1 || seen[e.Key] {
+			t.Errorf("streamed %+v, want a unique source item", e)
+		}
+		seen[e.Key] = true
+	}
+	if len(seen) != items {
+		t.Errorf("streamed items = %d, want %d", len(seen), items)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.Stream(ctx)
+	for i := 0; i < 10; i++ {
+		<-ch
+	}
+	cancel()
+	// the channel is closed early, a ready send may still be chosen over the cancellation
+	var rest int
+	for range ch {
+		rest++
+	}
+	if rest >= items-10 {
+		t.Errorf("streamed %d items after the cancellation, want it stopped early", rest)
+	}
+	for i := 0; runtime.NumGoroutine() > goroutines && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("goroutines = %d after the cancellation, want %d", n, goroutines)
+	}
+}
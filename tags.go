@@ -0,0 +1,132 @@
+package expirecache
+
+// WithTags attaches tags to the item, for removing tagged items together with PopTag.
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = tags
+	}
+}
+
+// linkTags adds the item k to the tags index
+func (ec *Cache[K, T]) linkTags(k K, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	if ec.tags == nil {
+		ec.tags = make(map[string]map[K]struct{})
+	}
+	for _, tag := range tags {
+		keys, ok := ec.tags[tag]
+		if !ok {
+			if ec.maxTags > 0 && len(ec.tags) >= ec.maxTags {
+				if !ec.dropLeastUsedTag {
+					continue
+				}
+				ec.dropLeastUsed()
+			}
+			keys = make(map[K]struct{})
+			ec.tags[tag] = keys
+		}
+		keys[k] = struct{}{}
+	}
+}
+
+// SetMaxTags limits the number of distinct tags, so the tags index memory stays bounded (0 for no limit).
+// When an item is stored with a new tag over the limit, the tag is ignored (PopTag doesn't find the item by it),
+// or, if dropLeastUsed is true, the index of the tag with the fewest items is dropped to make room
+// (the items stay in the cache, but PopTag doesn't find them by the dropped tag).
+func (ec *Cache[K, T]) SetMaxTags(n int, dropLeastUsed bool) {
+	ec.Lock()
+	ec.maxTags = n
+	ec.dropLeastUsedTag = dropLeastUsed
+	ec.Unlock()
+}
+
+// dropLeastUsed removes the tag with the fewest items from the tags index
+func (ec *Cache[K, T]) dropLeastUsed() {
+	var (
+		least string
+		n     = -1
+	)
+	for tag, keys := range ec.tags {
+		if n < 0 || len(keys) < n {
+			least, n = tag, len(keys)
+		}
+	}
+	delete(ec.tags, least)
+}
+
+// unlinkTags removes the item k from the tags index
+func (ec *Cache[K, T]) unlinkTags(k K, tags []string) {
+	for _, tag := range tags {
+		keys := ec.tags[tag]
+		delete(keys, k)
+		if len(keys) == 0 {
+			delete(ec.tags, tag)
+		}
+	}
+}
+
+// SetTagLimit limits the number of items with the tag (0 for no limit): storing an item with the tag
+// over the limit evicts the oldest stored items with the tag.
+func (ec *Cache[K, T]) SetTagLimit(tag string, max int) {
+	ec.Lock()
+	if max <= 0 {
+		delete(ec.tagLimits, tag)
+	} else {
+		if ec.tagLimits == nil {
+			ec.tagLimits = make(map[string]int)
+		}
+		ec.tagLimits[tag] = max
+	}
+	ec.Unlock()
+}
+
+// enforceTagLimits evicts the oldest items with the tags over their limits
+func (ec *Cache[K, T]) enforceTagLimits(tags []string) {
+	for _, tag := range tags {
+		max, ok := ec.tagLimits[tag]
+		if !ok {
+			continue
+		}
+		for len(ec.tags[tag]) > max {
+			var oldest *element[T]
+			for k := range ec.tags[tag] {
+				v := ec.elem(k)
+				if oldest == nil || v.created.Before(oldest.created) || (v.created.Equal(oldest.created) && v.seq < oldest.seq) {
+					oldest = v
+				}
+			}
+			ec.evictAt(oldest.keyIdx)
+		}
+	}
+}
+
+// PopTag removes all items with the tag under a single lock and returns the unexpired ones.
+// Like Delete, the items depending on the removed ones are invalidated too (see DependsOn).
+// It returns nil if the cache is read-only.
+func (ec *Cache[K, T]) PopTag(tag string) map[K]T {
+	now := timeNow()
+	ec.Lock()
+	if ec.readOnly {
+		ec.Unlock()
+		return nil
+	}
+	keys := make([]K, 0, len(ec.tags[tag]))
+	for k := range ec.tags[tag] {
+		keys = append(keys, k)
+	}
+	items := make(map[K]T, len(keys))
+	for _, k := range keys {
+		v := ec.removeAt(ec.elem(k).keyIdx)
+		if ec.alive(k, v, now) {
+			items[k] = v.data
+		}
+	}
+	// after all tagged items are popped, so none of them is lost by the cascade
+	for _, k := range keys {
+		ec.invalidateDependents(k)
+	}
+	ec.Unlock()
+	return items
+}
@@ -0,0 +1,134 @@
+package expirecache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCachePopTag(t *testing.T) {
+	c := New[string, string](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c.Set("a", "1", 1, 60, WithTags("foo"))
+	c.Set("b", "2", 1, 60, WithTags("foo", "bar"))
+	c.Set("c", "3", 1, 60, WithTags("bar"))
+	c.Set("d", "4", 1, 60)
+	c.Set("expired", "5", 1, 1, WithTags("foo"))
+	c.Set("derived", "6", 1, 60, c.DependsOn("a"))
+	// the overwrite drops the old tags
+	c.Set("e", "7", 1, 60, WithTags("foo"))
+	c.Set("e", "7", 1, 60)
+
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+
+	want := map[string]string{"a": "1", "b": "2"}
+	if got := c.PopTag("foo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("cache.PopTag(foo) = %v, want %v", got, want)
+	}
+	for k, present := range map[string]bool{"a": false, "b": false, "c": true, "d": true, "e": true, "expired": false, "derived": false} {
+		if _, ok := c.Inspect(k); ok != present {
+			t.Errorf("cache.Inspect(%s) = %v, want %v", k, ok, present)
+		}
+	}
+	if c.Items() != 3 || c.Size() != 3 {
+		t.Errorf("items, size = %d, %d, want 3, 3", c.Items(), c.Size())
+	}
+
+	if got := c.PopTag("foo"); len(got) != 0 {
+		t.Errorf("cache.PopTag(foo) again = %v, want empty", got)
+	}
+	if got := c.PopTag("bar"); !reflect.DeepEqual(got, map[string]string{"c": "3"}) {
+		t.Errorf("cache.PopTag(bar) = %v, want only c", got)
+	}
+	if len(c.tags) != 0 {
+		t.Errorf("tags index = %v, want empty", c.tags)
+	}
+}
+
+func TestCacheMaxTags(t *testing.T) {
+	for _, dropLeastUsed := range []bool{false, true} {
+		c := New[string, string](0)
+		c.SetMaxTags(2, dropLeastUsed)
+
+		c.Set("a", "1", 1, 60, WithTags("x"))
+		c.Set("b", "2", 1, 60, WithTags("y"))
+		c.Set("c", "3", 1, 60, WithTags("y"))
+		c.Set("d", "4", 1, 60, WithTags("z", "y"))
+
+		if len(c.tags) != 2 {
+			t.Errorf("dropLeastUsed %v: distinct tags = %d, want 2", dropLeastUsed, len(c.tags))
+		}
+		if c.Items() != 4 {
+			t.Errorf("dropLeastUsed %v: items = %d, want 4", dropLeastUsed, c.Items())
+		}
+		// y isn't affected, z is ignored or x is dropped
+		want := map[string]map[string]string{
+			"x": {"a": "1"},
+			"z": {},
+			"y": {"b": "2", "c": "3", "d": "4"},
+		}
+		if dropLeastUsed {
+			want = map[string]map[string]string{
+				"x": {},
+				"z": {"d": "4"},
+				"y": {"b": "2", "c": "3"}, // d is popped by z
+			}
+		}
+		for _, tag := range []string{"x", "z", "y"} {
+			if got := c.PopTag(tag); !reflect.DeepEqual(got, want[tag]) {
+				t.Errorf("dropLeastUsed %v: cache.PopTag(%s) = %v, want %v", dropLeastUsed, tag, got, want[tag])
+			}
+		}
+	}
+}
+
+func TestCacheTagLimit(t *testing.T) {
+	c := New[int, int](0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	c.SetTagLimit("x", 3)
+
+	for i := 0; i < 6; i++ {
+		at := t0.Add(time.Duration(i) * time.Second)
+		timeNow = func() time.Time { return at }
+		c.Set(i, i, 1, 60, WithTags("x"))
+		c.Set(100+i, i, 1, 60, WithTags("y"))
+		if i == 3 {
+			// the overwrite makes 1 newer than 3
+			timeNow = func() time.Time { return at.Add(time.Millisecond) }
+			c.Set(1, 1, 1, 60, WithTags("x"))
+		}
+	}
+
+	if len(c.tags["x"]) != 3 {
+		t.Errorf("items with the tag = %d, want 3", len(c.tags["x"]))
+	}
+	for k, present := range map[int]bool{0: false, 1: true, 2: false, 3: false, 4: true, 5: true} {
+		if _, ok := c.Get(k); ok != present {
+			t.Errorf("cache.Get(%d) = %v, want %v", k, ok, present)
+		}
+	}
+	if len(c.tags["y"]) != 6 {
+		t.Errorf("items with the unlimited tag = %d, want 6", len(c.tags["y"]))
+	}
+	if st := c.Stats(); st.Evictions != 3 {
+		t.Errorf("evictions = %d, want 3", st.Evictions)
+	}
+
+	c.SetTagLimit("x", 0)
+	c.Set(6, 6, 1, 60, WithTags("x"))
+	if len(c.tags["x"]) != 4 {
+		t.Errorf("items with the tag = %d after removing the limit, want 4", len(c.tags["x"]))
+	}
+}
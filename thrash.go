@@ -0,0 +1,87 @@
+package expirecache
+
+import "time"
+
+// thrashTracker records recently evicted keys, to find keys reinserted soon after the eviction
+type thrashTracker[K comparable] struct {
+	window time.Duration
+
+	evicted    []evictedKey[K] // ring buffer
+	evictedPos int
+	evictedIdx map[K]int // index in evicted
+
+	thrashing    []K // ring buffer, unique keys
+	thrashingPos int
+	thrashingSet map[K]struct{}
+}
+
+type evictedKey[K comparable] struct {
+	k  K
+	at time.Time
+}
+
+// SetThrashTracking enables tracking of the last size evicted keys, reporting by ThrashingKeys
+// (up to size) keys reinserted within window after the eviction. A zero size disables the tracking.
+func (ec *Cache[K, T]) SetThrashTracking(size int, window time.Duration) {
+	ec.Lock()
+	if size <= 0 {
+		ec.thrash = nil
+	} else {
+		ec.thrash = &thrashTracker[K]{
+			window:       window,
+			evicted:      make([]evictedKey[K], 0, size),
+			evictedIdx:   make(map[K]int, size),
+			thrashing:    make([]K, 0, size),
+			thrashingSet: make(map[K]struct{}, size),
+		}
+	}
+	ec.Unlock()
+}
+
+// ThrashingKeys returns the recent keys evicted due to the maximum memory size and reinserted soon after,
+// candidates for a higher priority or a longer TTL.
+func (ec *Cache[K, T]) ThrashingKeys() []K {
+	ec.RLock()
+	if ec.thrash == nil {
+		ec.RUnlock()
+		return nil
+	}
+	keys := make([]K, len(ec.thrash.thrashing))
+	copy(keys, ec.thrash.thrashing)
+	ec.RUnlock()
+	return keys
+}
+
+func (tt *thrashTracker[K]) evict(k K, now time.Time) {
+	if i, ok := tt.evictedIdx[k]; ok {
+		tt.evicted[i].at = now
+		return
+	}
+	if len(tt.evicted) < cap(tt.evicted) {
+		tt.evictedIdx[k] = len(tt.evicted)
+		tt.evicted = append(tt.evicted, evictedKey[K]{k: k, at: now})
+		return
+	}
+	delete(tt.evictedIdx, tt.evicted[tt.evictedPos].k)
+	tt.evicted[tt.evictedPos] = evictedKey[K]{k: k, at: now}
+	tt.evictedIdx[k] = tt.evictedPos
+	tt.evictedPos = (tt.evictedPos + 1) % len(tt.evicted)
+}
+
+func (tt *thrashTracker[K]) insert(k K, now time.Time) {
+	i, ok := tt.evictedIdx[k]
+	if !ok || now.Sub(tt.evicted[i].at) > tt.window {
+		return
+	}
+	if _, ok = tt.thrashingSet[k]; ok {
+		return
+	}
+	tt.thrashingSet[k] = struct{}{}
+	if len(tt.thrashing) < cap(tt.thrashing) {
+		tt.thrashing = append(tt.thrashing, k)
+		return
+	}
+	delete(tt.thrashingSet, tt.thrashing[tt.thrashingPos])
+	tt.thrashing[tt.thrashingPos] = k
+	tt.thrashingPos = (tt.thrashingPos + 1) % len(tt.thrashing)
+}
@@ -0,0 +1,68 @@
+package expirecache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCacheThrashingKeys(t *testing.T) {
+	c := New[int, int](3)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	if keys := c.ThrashingKeys(); keys != nil {
+		t.Errorf("ThrashingKeys() without tracking = %v, want nil", keys)
+	}
+	c.SetThrashTracking(2, time.Minute)
+
+	// only 0 and 1 are evicted
+	c.Set(0, 0, 1, 3600, WithPriority(PriorityLow))
+	c.Set(1, 1, 1, 3600, WithPriority(PriorityLow))
+	c.Set(2, 2, 1, 3600)
+	c.Set(3, 3, 2, 3600)
+	if _, ok := c.Get(0); ok {
+		t.Fatalf("0 should be evicted")
+	}
+	if _, ok := c.Get(1); ok {
+		t.Fatalf("1 should be evicted")
+	}
+	if keys := c.ThrashingKeys(); len(keys) != 0 {
+		t.Errorf("ThrashingKeys() before reinsert = %v, want empty", keys)
+	}
+
+	// evict-and-reinsert within the window
+	timeNow = func() time.Time { return t0.Add(30 * time.Second) }
+	c.Set(0, 0, 1, 3600, WithPriority(PriorityHigh))
+	c.Set(0, 0, 1, 3600, WithPriority(PriorityHigh))
+	if keys := c.ThrashingKeys(); !reflect.DeepEqual(keys, []int{0}) {
+		t.Errorf("ThrashingKeys() = %v, want [0]", keys)
+	}
+
+	// outside of the window
+	timeNow = func() time.Time { return t0.Add(2 * time.Minute) }
+	c.Set(1, 1, 1, 3600, WithPriority(PriorityHigh))
+	if keys := c.ThrashingKeys(); !reflect.DeepEqual(keys, []int{0}) {
+		t.Errorf("ThrashingKeys() = %v, want [0]", keys)
+	}
+
+	// bounded by the tracking size
+	c = New[int, int](1)
+	c.SetThrashTracking(2, time.Minute)
+	c.Set(-1, -1, 1, 3600)
+	for i := 0; i < 4; i++ {
+		c.Set(i, i, 1, 3600, WithPriority(PriorityLow)) // evicted immediately
+		c.Set(i, i, 1, 3600, WithPriority(PriorityLow)) // reinserted
+	}
+	keys := c.ThrashingKeys()
+	sort.Ints(keys)
+	if !reflect.DeepEqual(keys, []int{2, 3}) {
+		t.Errorf("ThrashingKeys() = %v, want [2 3]", keys)
+	}
+}
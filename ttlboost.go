@@ -0,0 +1,55 @@
+package expirecache
+
+import "time"
+
+// SetTTLBoost extends the TTLs while the cache is far from full, trading the spare room for hits: while the fullness
+// (of the maximum memory size or of the maximum items count, see New2Q, the larger one) is below below, the TTLs of the Sets
+// are multiplied by up to factor, decreasing linearly to the nominal TTLs as the fullness reaches below.
+// The extended TTLs are capped at max, but never cut below the nominal ones. The TTLs of a cache with no limits
+// aren't extended. A factor of 1 or less, or a zero max, disables the boost.
+func (ec *Cache[K, T]) SetTTLBoost(below, factor float64, max time.Duration) {
+	ec.Lock()
+	if factor <= 1 || max <= 0 {
+		below, factor, max = 0, 0, 0
+	}
+	ec.boostBelow = below
+	ec.boostFactor = factor
+	ec.boostMax = max
+	ec.Unlock()
+}
+
+// ttl returns the TTL of a Set with the expiration time in seconds, extended by the TTL boost.
+// Must be called under the lock.
+func (ec *Cache[K, T]) ttl(expire int32) time.Duration {
+	ttl := time.Duration(expire) * time.Second
+	if ttl <= 0 || ec.boostFactor <= 1 {
+		return ttl
+	}
+	fullness, limited := ec.fullness()
+	if !limited || fullness >= ec.boostBelow {
+		return ttl
+	}
+	boosted := float64(ttl) * (1 + (ec.boostFactor-1)*(1-fullness/ec.boostBelow))
+	if boosted > float64(ec.boostMax) {
+		boosted = float64(ec.boostMax)
+	}
+	if time.Duration(boosted) < ttl {
+		return ttl
+	}
+	return time.Duration(boosted)
+}
+
+// fullness returns the used share of the maximum memory size or of the maximum items count, the larger one.
+// It returns false if the cache has no limits.
+func (ec *Cache[K, T]) fullness() (fullness float64, limited bool) {
+	if ec.maxSize > 0 {
+		fullness, limited = float64(ec.totalSize)/float64(ec.maxSize), true
+	}
+	if ec.maxEntries > 0 {
+		if f := float64(len(ec.keys)) / float64(ec.maxEntries); f > fullness {
+			fullness = f
+		}
+		limited = true
+	}
+	return fullness, limited
+}
@@ -0,0 +1,84 @@
+package expirecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheTTLBoost(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c := New[int, int](1000)
+	c.SetTTLBoost(0.5, 3, time.Hour)
+
+	ttl := func(k int) time.Duration {
+		info, ok := c.Inspect(k)
+		if !ok {
+			t.Fatalf("cache.Inspect(%d) should be present", k)
+		}
+		return info.TTL
+	}
+
+	// empty, the full boost
+	c.Set(1, 1, 250, 60)
+	if got := ttl(1); got != 3*time.Minute {
+		t.Errorf("TTL at the fullness 0 = %v, want %v", got, 3*time.Minute)
+	}
+	// a quarter full, a half of the boost
+	if got := c.EffectiveTTL(2, 60); got != 2*time.Minute {
+		t.Errorf("cache.EffectiveTTL(2, 60) at the fullness 0.25 = %v, want %v", got, 2*time.Minute)
+	}
+	// capped, but not below the nominal TTL
+	if got := c.EffectiveTTL(3, 2400); got != time.Hour {
+		t.Errorf("cache.EffectiveTTL(3, 2400) = %v, want %v", got, time.Hour)
+	}
+	if got := c.EffectiveTTL(3, 7200); got != 2*time.Hour {
+		t.Errorf("cache.EffectiveTTL(3, 7200) = %v, want %v", got, 2*time.Hour)
+	}
+	if got := c.EffectiveTTL(3, -1); got != -time.Second {
+		t.Errorf("cache.EffectiveTTL(3, -1) = %v, want %v", got, -time.Second)
+	}
+	c.Set(2, 2, 250, 60)
+	if got := ttl(2); got != 2*time.Minute {
+		t.Errorf("TTL at the fullness 0.25 = %v, want %v", got, 2*time.Minute)
+	}
+	// a half full, the nominal TTLs
+	c.Set(3, 3, 250, 60)
+	if got := ttl(3); got != time.Minute {
+		t.Errorf("TTL at the fullness 0.5 = %v, want %v", got, time.Minute)
+	}
+	c.Set(4, 4, 200, 60)
+	if got := ttl(4); got != time.Minute {
+		t.Errorf("TTL at the fullness 0.75 = %v, want %v", got, time.Minute)
+	}
+
+	cfg := c.Config()
+	if cfg.TTLBoostBelow != 0.5 || cfg.TTLBoostFactor != 3 || cfg.TTLBoostMax != time.Hour {
+		t.Errorf("cache.Config() = %+v, want the TTL boost", cfg)
+	}
+	c.ClearAll()
+	c.SetTTLBoost(0.5, 1, time.Hour)
+	if got := c.EffectiveTTL(1, 60); got != time.Minute {
+		t.Errorf("cache.EffectiveTTL(1, 60) of the disabled boost = %v, want %v", got, time.Minute)
+	}
+
+	// no limits, no boost
+	c = New[int, int](0)
+	c.SetTTLBoost(0.5, 3, time.Hour)
+	if got := c.EffectiveTTL(1, 60); got != time.Minute {
+		t.Errorf("cache.EffectiveTTL(1, 60) of the unlimited cache = %v, want %v", got, time.Minute)
+	}
+
+	// the items count limit
+	c = New2Q[int, int](0, 4)
+	c.SetTTLBoost(1, 2, time.Hour)
+	c.Set(1, 1, 1, 60)
+	c.Set(2, 2, 1, 60)
+	if got := c.EffectiveTTL(3, 60); got != 90*time.Second {
+		t.Errorf("cache.EffectiveTTL(3, 60) at the fullness 0.5 = %v, want %v", got, 90*time.Second)
+	}
+}
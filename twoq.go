@@ -0,0 +1,152 @@
+package expirecache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// New2Q creates a new cache with a maximum memory size and a maximum items count (0 for unlimited), evicting
+// with the 2Q policy, resistant to scans: new items enter a FIFO of the recent items, and only the items hit (or stored again)
+// while there are promoted to the main LRU. The oldest recent items are evicted first while the FIFO takes more than
+// a quarter of the items, so one-time accesses don't push out the frequently used items. The keys of the items evicted from the FIFO
+// are remembered (up to a half of the items), and such a key stored again goes straight to the main LRU.
+// The policy takes over the priorities (see WithPriority), the vetoed items are still skipped (see SetVetoEvict).
+func New2Q[K comparable, T any](maxSize uint64, maxEntries int, opts ...Option) *Cache[K, T] {
+	return New[K, T](maxSize, append(opts[:len(opts):len(opts)], func(o *options) {
+		o.maxEntries = maxEntries
+		o.twoQueue = true
+	})...)
+}
+
+// twoQueue keeps the 2Q eviction order of the keys, with its own lock since the hits are recorded with no cache lock held
+type twoQueue[K comparable] struct {
+	mu     sync.Mutex
+	recent *list.List // A1in, the front is the newest
+	ghosts *list.List // A1out, the keys evicted from recent, the front is the newest
+	main   *list.List // Am, the front is the most recently used
+	queued map[K]queuedKey
+	ghost  map[K]*list.Element
+}
+
+type queuedKey struct {
+	e    *list.Element
+	main bool
+}
+
+func newTwoQueue[K comparable]() *twoQueue[K] {
+	return &twoQueue[K]{
+		recent: list.New(),
+		ghosts: list.New(),
+		main:   list.New(),
+		queued: make(map[K]queuedKey),
+		ghost:  make(map[K]*list.Element),
+	}
+}
+
+// insert queues a new key, to the main LRU if it was evicted from the recent FIFO
+func (q *twoQueue[K]) insert(k K) {
+	q.mu.Lock()
+	if e, ok := q.ghost[k]; ok {
+		q.ghosts.Remove(e)
+		delete(q.ghost, k)
+		q.queued[k] = queuedKey{e: q.main.PushFront(k), main: true}
+	} else {
+		q.queued[k] = queuedKey{e: q.recent.PushFront(k)}
+	}
+	q.mu.Unlock()
+}
+
+// access records a hit or an overwrite of a queued key
+func (q *twoQueue[K]) access(k K) {
+	q.mu.Lock()
+	if qk, ok := q.queued[k]; ok {
+		if qk.main {
+			q.main.MoveToFront(qk.e)
+		} else {
+			q.recent.Remove(qk.e)
+			q.queued[k] = queuedKey{e: q.main.PushFront(k), main: true}
+		}
+	}
+	q.mu.Unlock()
+}
+
+// remove unqueues a removed key
+func (q *twoQueue[K]) remove(k K) {
+	q.mu.Lock()
+	if qk, ok := q.queued[k]; ok {
+		if qk.main {
+			q.main.Remove(qk.e)
+		} else {
+			q.recent.Remove(qk.e)
+		}
+		delete(q.queued, k)
+	}
+	q.mu.Unlock()
+}
+
+// evicted remembers the key evicted from the recent FIFO, up to maxGhosts keys
+func (q *twoQueue[K]) evicted(k K, maxGhosts int) {
+	q.mu.Lock()
+	if qk, ok := q.queued[k]; ok && !qk.main {
+		q.ghost[k] = q.ghosts.PushFront(k)
+		for q.ghosts.Len() > maxGhosts {
+			delete(q.ghost, q.ghosts.Remove(q.ghosts.Back()).(K))
+		}
+	}
+	q.mu.Unlock()
+}
+
+// victim returns the key to evict: the oldest recent one while the recent FIFO is over maxRecent keys,
+// the least recently used main one otherwise. The keys skipped by skip aren't evicted, false is returned if all are skipped.
+func (q *twoQueue[K]) victim(maxRecent int, skip func(k K) bool) (k K, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lists := [2]*list.List{q.main, q.recent}
+	if q.recent.Len() > maxRecent || q.main.Len() == 0 {
+		lists[0], lists[1] = q.recent, q.main
+	}
+	for _, l := range lists {
+		for e := l.Back(); e != nil; e = e.Prev() {
+			if k := e.Value.(K); skip == nil || !skip(k) {
+				return k, true
+			}
+		}
+	}
+	return k, false
+}
+
+func (q *twoQueue[K]) reset() {
+	q.mu.Lock()
+	q.recent.Init()
+	q.ghosts.Init()
+	q.main.Init()
+	q.queued = make(map[K]queuedKey)
+	q.ghost = make(map[K]*list.Element)
+	q.mu.Unlock()
+}
+
+// twoQueueVictim returns the index in ec.keys of the 2Q victim, -1 if all items are vetoed
+func (ec *Cache[K, T]) twoQueueVictim() int {
+	var skip func(k K) bool
+	if ec.vetoEvict != nil {
+		skip = func(k K) bool { return ec.vetoed(ec.elem(k).keyIdx) }
+	}
+	k, ok := ec.twoQ.victim(ec.twoQueueCapacity()/4, skip)
+	if !ok {
+		return -1
+	}
+	return ec.elem(k).keyIdx
+}
+
+// twoQueueCapacity returns the items count the 2Q queues are sized for: the maximum, or the current one if unlimited
+func (ec *Cache[K, T]) twoQueueCapacity() int {
+	if ec.maxEntries > 0 {
+		return ec.maxEntries
+	}
+	return len(ec.keys)
+}
+
+// overEntries checks if the items count is over the maximum
+func (ec *Cache[K, T]) overEntries() bool {
+	return ec.maxEntries > 0 && len(ec.keys) > ec.maxEntries
+}
@@ -0,0 +1,149 @@
+package expirecache
+
+import (
+	"container/list"
+	"sync"
+	"testing"
+)
+
+// lruKeys is a plain LRU of keys, the reference for the 2Q policy
+type lruKeys struct {
+	capacity int
+	order    *list.List // the most recently used first
+	elems    map[int]*list.Element
+}
+
+func newLRUKeys(capacity int) *lruKeys {
+	return &lruKeys{capacity: capacity, order: list.New(), elems: make(map[int]*list.Element)}
+}
+
+func (l *lruKeys) access(k int) {
+	if e, ok := l.elems[k]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elems[k] = l.order.PushFront(k)
+	if l.order.Len() > l.capacity {
+		delete(l.elems, l.order.Remove(l.order.Back()).(int))
+	}
+}
+
+func TestCache2QScan(t *testing.T) {
+	const (
+		hot      = 20
+		capacity = 100
+	)
+	c := New2Q[int, int](0, capacity)
+	lru := newLRUKeys(capacity)
+	for i := 0; i < hot; i++ {
+		c.Set(i, i, 1, 60)
+		c.Get(i)
+		lru.access(i)
+		lru.access(i)
+	}
+	// a scan of the one-time accesses
+	for i := 1000; i < 2000; i++ {
+		c.Set(i, i, 1, 60)
+		lru.access(i)
+	}
+
+	if c.Items() != capacity {
+		t.Errorf("items = %d, want %d", c.Items(), capacity)
+	}
+	for i := 0; i < hot; i++ {
+		if _, ok := c.Get(i); !ok {
+			t.Errorf("cache.Get(%d) of the hot item should survive the scan", i)
+		}
+	}
+	// the same accesses evict all hot items from a plain LRU
+	for i := 0; i < hot; i++ {
+		if _, ok := lru.elems[i]; ok {
+			t.Errorf("the hot item %d survived the scan in the plain LRU, want evicted", i)
+		}
+	}
+	if st := c.Stats(); st.Evictions != 1000+hot-capacity {
+		t.Errorf("evictions = %d, want %d", st.Evictions, 1000+hot-capacity)
+	}
+}
+
+func TestCache2QGhosts(t *testing.T) {
+	// 2 recent keys, 4 ghost keys
+	c := New2Q[int, int](0, 8)
+	for i := 0; i < 6; i++ {
+		c.Set(i, i, 1, 60)
+		c.Get(i)
+	}
+	c.Set(100, 100, 1, 60)
+	c.Set(101, 101, 1, 60)
+	c.Set(102, 102, 1, 60) // evicts 100 from the recent FIFO
+	if _, ok := c.Inspect(100); ok {
+		t.Fatalf("cache.Inspect(100) of the oldest recent item should miss")
+	}
+	// stored again, the remembered key goes to the main LRU and evicts its oldest item
+	c.Set(100, 100, 1, 60)
+	if _, ok := c.Inspect(0); ok {
+		t.Errorf("cache.Inspect(0) of the least recently used item should miss")
+	}
+	for i := 103; i < 110; i++ {
+		c.Set(i, i, 1, 60)
+	}
+	if _, ok := c.Inspect(100); !ok {
+		t.Errorf("cache.Inspect(100) of the promoted item should survive the scan")
+	}
+
+	// the memory size limit applies too
+	c = New2Q[int, int](10, 0)
+	for i := 0; i < 3; i++ {
+		c.Set(i, i, 3, 60)
+		c.Get(i)
+	}
+	// with no items count limit, the recent FIFO takes up to a quarter of the current items
+	c.Set(100, 100, 3, 60)
+	c.Set(101, 101, 3, 60)
+	if c.Size() > 10 {
+		t.Errorf("size = %d, want at most 10", c.Size())
+	}
+	for k, want := range map[int]bool{0: false, 1: true, 2: true, 100: false, 101: true} {
+		if _, ok := c.Inspect(k); ok != want {
+			t.Errorf("cache.Inspect(%d) = %v, want %v", k, ok, want)
+		}
+	}
+
+	// the queues are emptied with the items
+	c.ClearAll()
+	c.Set(1, 1, 3, 60)
+	c.Delete(1)
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, 3, 60)
+	}
+	if c.Items() != 3 {
+		t.Errorf("items = %d, want 3", c.Items())
+	}
+	if cfg := c.Config(); !cfg.TwoQueue || cfg.MaxEntries != 0 {
+		t.Errorf("cache.Config() = %+v, want TwoQueue", cfg)
+	}
+}
+
+func TestCache2QConcurrent(t *testing.T) {
+	c := New2Q[int, int](0, 50)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := random(0, 200)
+				if _, ok := c.Get(k); !ok {
+					c.Set(k, k, 1, 60)
+				}
+				if i%100 == g {
+					c.Delete(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Items() > 50 {
+		t.Errorf("items = %d, want at most 50", c.Items())
+	}
+}
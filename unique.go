@@ -0,0 +1,95 @@
+package expirecache
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// uniqueKeys is a HyperLogLog estimator of the distinct keys count
+type uniqueKeys[K comparable] struct {
+	mu        sync.Mutex
+	hash      func(k K) uint64
+	precision uint8
+	registers []uint8
+}
+
+// SetUniqueKeysTracking enables the estimation of the distinct keys looked up or stored over the cache lifetime
+// (not only the current items), reported by UniqueKeysEstimate, e.g. for comparing the working set with the cache size.
+// It's a HyperLogLog of 2^precision bytes (the precision is clamped to [4, 16]) with the standard error
+// of about 1.04/sqrt(2^precision), e.g. 0.8% for 14. hash must distribute the keys well, it's invoked under the write lock
+// by the stores (and with no lock held by the lookups), so it must not call back into the cache. A nil hash disables the estimation.
+func (ec *Cache[K, T]) SetUniqueKeysTracking(hash func(k K) uint64, precision int) {
+	if hash == nil {
+		ec.unique.Store((*uniqueKeys[K])(nil))
+		return
+	}
+	if precision < 4 {
+		precision = 4
+	} else if precision > 16 {
+		precision = 16
+	}
+	ec.unique.Store(&uniqueKeys[K]{hash: hash, precision: uint8(precision), registers: make([]uint8, 1<<precision)})
+}
+
+// UniqueKeysEstimate returns the estimated count of the distinct keys looked up or stored since
+// SetUniqueKeysTracking, 0 if the estimation isn't enabled.
+func (ec *Cache[K, T]) UniqueKeysEstimate() uint64 {
+	if u, _ := ec.unique.Load().(*uniqueKeys[K]); u != nil {
+		return u.estimate()
+	}
+	return 0
+}
+
+// seen feeds the key to the unique keys estimator, safe to call with no lock held
+func (ec *Cache[K, T]) seen(k K) {
+	if u, _ := ec.unique.Load().(*uniqueKeys[K]); u != nil {
+		u.add(k)
+	}
+}
+
+func (u *uniqueKeys[K]) add(k K) {
+	h := mix64(u.hash(k))
+	idx := h >> (64 - u.precision)
+	// the position of the first set bit of the rest, guarded against all zeros
+	rank := uint8(bits.LeadingZeros64(h<<u.precision|1<<(u.precision-1))) + 1
+	u.mu.Lock()
+	if rank > u.registers[idx] {
+		u.registers[idx] = rank
+	}
+	u.mu.Unlock()
+}
+
+func (u *uniqueKeys[K]) estimate() uint64 {
+	m := float64(len(u.registers))
+	var (
+		sum   float64
+		zeros int
+	)
+	u.mu.Lock()
+	for _, r := range u.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	u.mu.Unlock()
+
+	var alpha float64
+	switch len(u.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// the linear counting for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
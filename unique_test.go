@@ -0,0 +1,53 @@
+package expirecache
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCacheUniqueKeysEstimate(t *testing.T) {
+	c := New[int, int](100)
+	if n := c.UniqueKeysEstimate(); n != 0 {
+		t.Errorf("cache.UniqueKeysEstimate() without the estimation = %d, want 0", n)
+	}
+	c.SetUniqueKeysTracking(func(k int) uint64 { return uint64(k) }, 14)
+	if cfg := c.Config(); cfg.UniqueKeys != 14 {
+		t.Errorf("cache.Config().UniqueKeys = %d, want 14", cfg.UniqueKeys)
+	}
+
+	// 3 standard errors
+	bound := 3 * 1.04 / math.Sqrt(1<<14)
+	check := func(want int) {
+		t.Helper()
+		n := c.UniqueKeysEstimate()
+		if err := math.Abs(float64(n)-float64(want)) / float64(want); err > bound {
+			t.Errorf("cache.UniqueKeysEstimate() = %d, want %d within %.1f%%", n, want, bound*100)
+		}
+	}
+
+	// repeated keys are counted once
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i, 1, 60)
+		c.Get(i)
+		c.Get(i)
+	}
+	check(1000)
+
+	// far more keys than the items, misses are counted too
+	for i := 1000; i < 100000; i++ {
+		if i%2 == 0 {
+			c.Set(i, i, 1, 60)
+		} else {
+			c.Get(i)
+		}
+	}
+	check(100000)
+	if c.Items() > 100 {
+		t.Errorf("items = %d, want at most 100", c.Items())
+	}
+
+	c.SetUniqueKeysTracking(nil, 0)
+	if n := c.UniqueKeysEstimate(); n != 0 {
+		t.Errorf("cache.UniqueKeysEstimate() of the disabled estimation = %d, want 0", n)
+	}
+}
@@ -0,0 +1,22 @@
+package expirecache
+
+// SetVetoEvict sets a function protecting items from eviction due to the maximum memory size (e.g. items about
+// to be needed): if f returns true for the chosen victim, the next candidate is chosen instead. If all items are vetoed,
+// nothing is evicted and the cache stays over the maximum memory size until the items expire or are deleted.
+// Finding a victim with a veto is O(n). f is invoked under the write lock (also for the victims of New2Q),
+// so it must not call back into the cache.
+// Pass nil to disable.
+func (ec *Cache[K, T]) SetVetoEvict(f func(k K, v T) bool) {
+	ec.Lock()
+	ec.vetoEvict = f
+	ec.Unlock()
+}
+
+// vetoed checks if the eviction of the item for the key at slot in ec.keys is vetoed
+func (ec *Cache[K, T]) vetoed(slot int) bool {
+	if ec.vetoEvict == nil {
+		return false
+	}
+	k := ec.keys[slot]
+	return ec.vetoEvict(k, ec.elem(k).data)
+}
@@ -0,0 +1,68 @@
+package expirecache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCacheVetoEvict(t *testing.T) {
+	for _, mixed := range []bool{false, true} {
+		c := New[string, int](10)
+		var vetoes int
+		c.SetVetoEvict(func(k string, v int) bool {
+			vetoes++
+			return strings.HasPrefix(k, "keep")
+		})
+		c.SetOnSpill(func(k string, v int) {
+			if strings.HasPrefix(k, "keep") {
+				t.Errorf("mixed %v: %s is evicted", mixed, k)
+			}
+		}, false)
+
+		for i := 0; i < 3; i++ {
+			// the protected items are the first victims with mixed priorities
+			c.Set(fmt.Sprintf("keep%d", i), i, 1, 60, WithPriority(PriorityLow))
+		}
+		for i := 0; i < 100; i++ {
+			if mixed {
+				c.Set(fmt.Sprintf("key%d", i), i, 1, 60, WithPriority(PriorityHigh))
+			} else {
+				c.Set(fmt.Sprintf("key%d", i), i, 1, 60, WithPriority(PriorityLow))
+			}
+		}
+		if vetoes == 0 {
+			t.Errorf("mixed %v: the veto is never consulted", mixed)
+		}
+		for i := 0; i < 3; i++ {
+			if _, ok := c.Get(fmt.Sprintf("keep%d", i)); !ok {
+				t.Errorf("mixed %v: cache.Get(keep%d) should be present", mixed, i)
+			}
+		}
+		if c.Size() != 10 || c.Stats().Evictions != 93 {
+			t.Errorf("mixed %v: size = %d, evictions %d, want 10, 93", mixed, c.Size(), c.Stats().Evictions)
+		}
+		if n := c.Reserve(8, 8); n != 7 || c.Items() != 3 {
+			t.Errorf("mixed %v: cache.Reserve(8) = %d, items %d, want 7, 3", mixed, n, c.Items())
+		}
+	}
+}
+
+func TestCacheVetoEvictAll(t *testing.T) {
+	c := New[int, int](10)
+	c.SetVetoEvict(func(k, v int) bool { return true })
+
+	// gives up instead of looping forever
+	for i := 0; i < 20; i++ {
+		c.Set(i, i, 1, 60)
+	}
+	if c.Items() != 20 || c.Stats().Evictions != 0 {
+		t.Errorf("items = %d, evictions %d, want 20, 0", c.Items(), c.Stats().Evictions)
+	}
+
+	c.SetVetoEvict(nil)
+	c.Set(100, 100, 1, 60)
+	if c.Size() != 10 {
+		t.Errorf("size = %d without the veto, want 10", c.Size())
+	}
+}
@@ -0,0 +1,143 @@
+package expirecache
+
+import (
+	"sync"
+	"time"
+)
+
+// pendingWrite is a buffered Set or Delete
+type pendingWrite[T any] struct {
+	v          T
+	size       uint64
+	validUntil time.Time // by the nominal TTL, the TTL boost (see SetTTLBoost) is applied on flush
+	expire     int32
+	deleted    bool
+}
+
+// WriteBuffer coalesces Sets to the cache: the latest value per key is kept in memory
+// and applied to the cache in a batch under a single lock hold on Flush.
+// Deletes are buffered too. Get returns the buffered values and misses the buffered deletes. It's safe for concurrent use.
+type WriteBuffer[K comparable, T any] struct {
+	mu         sync.RWMutex
+	ec         *Cache[K, T]
+	pending    map[K]pendingWrite[T]
+	flushing   map[K]pendingWrite[T] // the items being applied by a flush, still returned by Get
+	maxPending int
+	// flushMu serializes the flushes, so an earlier batch never overwrites a later one
+	flushMu sync.Mutex
+}
+
+// NewWriteBuffer creates a write buffer for the cache, flushed when maxPending keys are buffered
+// (0 for no limit) and by Flusher.
+func NewWriteBuffer[K comparable, T any](ec *Cache[K, T], maxPending int) *WriteBuffer[K, T] {
+	return &WriteBuffer[K, T]{
+		ec:         ec,
+		pending:    make(map[K]pendingWrite[T]),
+		maxPending: maxPending,
+	}
+}
+
+// Set buffers an item like Cache.Set, with no cache lock taken. The expiration time is counted from now, not from the flush,
+// the TTL boost (see Cache.SetTTLBoost) is added on the flush, by the cache fullness then.
+func (wb *WriteBuffer[K, T]) Set(k K, v T, size uint64, expire int32) {
+	validUntil := timeNow().Add(time.Duration(expire) * time.Second)
+	wb.buffer(k, pendingWrite[T]{v: v, size: size, validUntil: validUntil, expire: expire})
+}
+
+// Delete buffers a removal of the item like Cache.Delete, replacing a buffered Set of it.
+func (wb *WriteBuffer[K, T]) Delete(k K) {
+	wb.buffer(k, pendingWrite[T]{deleted: true})
+}
+
+func (wb *WriteBuffer[K, T]) buffer(k K, p pendingWrite[T]) {
+	wb.mu.Lock()
+	wb.pending[k] = p
+	full := wb.maxPending > 0 && len(wb.pending) >= wb.maxPending
+	wb.mu.Unlock()
+	// with no buffer lock held, so Get and the other writers aren't blocked by the flush
+	if full {
+		wb.Flush()
+	}
+}
+
+// Get returns the buffered item, or the item from the cache if there is no buffered one.
+// It misses the item with a buffered Delete.
+func (wb *WriteBuffer[K, T]) Get(k K) (item T, ok bool) {
+	wb.mu.RLock()
+	p, buffered := wb.pending[k]
+	if !buffered {
+		p, buffered = wb.flushing[k]
+	}
+	wb.mu.RUnlock()
+	if !buffered {
+		// a flush applies the buffered items before removing them from the buffer, so they aren't missed
+		return wb.ec.Get(k)
+	}
+	if p.deleted || p.validUntil.Before(timeNow()) {
+		return item, false
+	}
+	return p.v, true
+}
+
+// Flush applies the buffered items and deletes to the cache and returns their count.
+// An item expired in the meantime deletes the stored one (the Set replaced it), items over the cache Set rate limit
+// (see WithSetRateLimit) are dropped, as are all items if the cache is read-only.
+// The buffered items are swapped out under the buffer lock and applied with it released,
+// so Sets, Deletes and Gets of the buffer proceed during the flush.
+func (wb *WriteBuffer[K, T]) Flush() int {
+	wb.flushMu.Lock()
+	wb.mu.Lock()
+	batch := wb.pending
+	if len(batch) == 0 {
+		wb.mu.Unlock()
+		wb.flushMu.Unlock()
+		return 0
+	}
+	wb.pending = make(map[K]pendingWrite[T])
+	wb.flushing = batch
+	wb.mu.Unlock()
+
+	var n int
+	now := timeNow()
+	ec := wb.ec
+	ec.Lock()
+	if !ec.readOnly {
+		for k, p := range batch {
+			// an expired Set replaced the stored item too, so it's removed like by a Delete
+			if ttl := p.validUntil.Sub(now); p.deleted || ttl < 0 {
+				ec.invalidateDependents(k)
+				if v, ok := ec.get(k); ok {
+					ec.removeAt(v.keyIdx)
+				}
+				n++
+			} else if ec.allowSet() {
+				// the remaining TTL extended by the TTL boost for the fullness at the flush
+				boost := ec.ttl(p.expire) - time.Duration(p.expire)*time.Second
+				ec.actualSet(k, p.v, p.size, ttl+boost)
+				n++
+			}
+		}
+	}
+	wb.mu.Lock()
+	wb.flushing = nil
+	wb.mu.Unlock()
+	// the next flush waits for the cache lock, so the callbacks run with no buffer lock held
+	wb.flushMu.Unlock()
+	ec.unlock()
+	return n
+}
+
+// Flusher flushes the buffer every d until exit is closed, then flushes it for the last time.
+func (wb *WriteBuffer[K, T]) Flusher(d time.Duration, exit <-chan struct{}) {
+	t := time.NewTicker(d)
+	for {
+		select {
+		case <-exit:
+			t.Stop()
+			wb.Flush()
+			return
+		case <-t.C:
+			wb.Flush()
+		}
+	}
+}
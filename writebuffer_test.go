@@ -0,0 +1,209 @@
+package expirecache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	c := New[string, int](0)
+	wb := NewWriteBuffer(c, 0)
+
+	defer func() {
+		timeNow = time.Now
+	}()
+
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	for i := 1; i <= 100; i++ {
+		wb.Set("foo", i, 1, 10)
+		if v, ok := wb.Get("foo"); !ok || v != i {
+			t.Fatalf("wb.Get(foo) = (%d, %v), want (%d, true)", v, ok, i)
+		}
+	}
+	wb.Set("bar", 1, 1, 10)
+	if _, ok := c.Get("foo"); ok {
+		t.Errorf("cache.Get(foo) before the flush should miss")
+	}
+
+	if n := wb.Flush(); n != 2 {
+		t.Errorf("wb.Flush() = %d, want 2", n)
+	}
+	if v, ok := c.Get("foo"); !ok || v != 100 {
+		t.Errorf("cache.Get(foo) = (%d, %v), want (100, true)", v, ok)
+	}
+	if v, ok := wb.Get("foo"); !ok || v != 100 {
+		t.Errorf("wb.Get(foo) = (%d, %v), want (100, true)", v, ok)
+	}
+	if info, _ := c.Inspect("foo"); info.TTL != 10*time.Second {
+		t.Errorf("cache.Inspect(foo) TTL = %v, want 10s", info.TTL)
+	}
+	if n := wb.Flush(); n != 0 {
+		t.Errorf("wb.Flush() of the empty buffer = %d, want 0", n)
+	}
+
+	// the expiration time counts from the buffered Set
+	c.Set("baz", 0, 1, 60)
+	wb.Set("baz", 1, 1, 1)
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+	if _, ok := wb.Get("baz"); ok {
+		t.Errorf("wb.Get(baz) of the expired buffered item should miss")
+	}
+	// and the expired item replaces the stored one
+	if n := wb.Flush(); n != 1 {
+		t.Errorf("wb.Flush() of the expired item = %d, want 1", n)
+	}
+	if _, ok := c.Get("baz"); ok {
+		t.Errorf("cache.Get(baz) after the flush of the expired item should miss")
+	}
+}
+
+func TestWriteBufferConcurrentFlush(t *testing.T) {
+	c := New[int, int](0)
+	wb := NewWriteBuffer(c, 16)
+	const writes = 1000
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				wb.Set(w, i, 1, 60)
+				// the own latest value, buffered, being flushed or in the cache
+				if v, ok := wb.Get(w); !ok || v != i {
+					t.Errorf("wb.Get(%d) = (%d, %v), want (%d, true)", w, v, ok, i)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	wb.Flush()
+	for w := 0; w < 4; w++ {
+		if v, ok := c.Get(w); !ok || v != writes-1 {
+			t.Errorf("cache.Get(%d) = (%d, %v), want (%d, true)", w, v, ok, writes-1)
+		}
+	}
+}
+
+func TestWriteBufferTTLBoost(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+
+	c := New[int, int](1000)
+	c.SetTTLBoost(0.5, 3, time.Hour)
+	wb := NewWriteBuffer(c, 0)
+
+	// buffered while the cache is empty, flushed a quarter full, 10 seconds later
+	wb.Set(1, 1, 1, 60)
+	c.Set(2, 2, 250, 3600)
+	timeNow = func() time.Time { return t0.Add(10 * time.Second) }
+	wb.Flush()
+	// the remaining nominal TTL with a half of the boost
+	if info, _ := c.Inspect(1); info.TTL != 110*time.Second {
+		t.Errorf("cache.Inspect(1) TTL = %v, want %v", info.TTL, 110*time.Second)
+	}
+}
+
+func TestWriteBufferMaxPending(t *testing.T) {
+	c := New[int, int](0)
+	wb := NewWriteBuffer(c, 3)
+
+	wb.Set(1, 1, 1, 10)
+	wb.Set(1, 2, 1, 10)
+	wb.Set(2, 1, 1, 10)
+	if c.Items() != 0 {
+		t.Errorf("items = %d before the threshold, want 0", c.Items())
+	}
+	wb.Set(3, 1, 1, 10)
+	if c.Items() != 3 {
+		t.Errorf("items = %d after the threshold, want 3", c.Items())
+	}
+}
+
+func TestWriteBufferDelete(t *testing.T) {
+	c := New[string, int](0)
+	wb := NewWriteBuffer(c, 0)
+
+	c.Set("foo", 1, 1, 10)
+	c.Set("bar", 1, 1, 10)
+	c.Set("dep", 1, 1, 10, c.DependsOn("foo"))
+
+	// read-your-writes before the flush
+	wb.Set("bar", 2, 1, 10)
+	if v, ok := wb.Get("bar"); !ok || v != 2 {
+		t.Errorf("wb.Get(bar) = (%d, %v), want (2, true)", v, ok)
+	}
+	wb.Delete("foo")
+	if _, ok := wb.Get("foo"); ok {
+		t.Errorf("wb.Get(foo) with the buffered delete should miss")
+	}
+	if _, ok := c.Get("foo"); !ok {
+		t.Errorf("cache.Get(foo) before the flush should be present")
+	}
+	// a delete replaces the buffered set and vice versa
+	wb.Delete("bar")
+	if _, ok := wb.Get("bar"); ok {
+		t.Errorf("wb.Get(bar) with the buffered delete should miss")
+	}
+	wb.Set("baz", 1, 1, 10)
+	wb.Delete("baz")
+	wb.Set("baz", 3, 1, 10)
+	if v, ok := wb.Get("baz"); !ok || v != 3 {
+		t.Errorf("wb.Get(baz) = (%d, %v), want (3, true)", v, ok)
+	}
+
+	if n := wb.Flush(); n != 3 {
+		t.Errorf("wb.Flush() = %d, want 3", n)
+	}
+	for k, present := range map[string]bool{"foo": false, "bar": false, "dep": false, "baz": true} {
+		if _, ok := c.Get(k); ok != present {
+			t.Errorf("cache.Get(%s) = %v, want %v", k, ok, present)
+		}
+		if _, ok := wb.Get(k); ok != present {
+			t.Errorf("wb.Get(%s) = %v, want %v", k, ok, present)
+		}
+	}
+
+	// an expired buffered set is flushed like a delete, with the dependents
+	defer func() {
+		timeNow = time.Now
+	}()
+	t0 := time.Now()
+	timeNow = func() time.Time { return t0 }
+	c.Set("dep", 1, 1, 10, c.DependsOn("baz"))
+	wb.Set("baz", 4, 1, 1)
+	timeNow = func() time.Time { return t0.Add(2 * time.Second) }
+	if n := wb.Flush(); n != 1 {
+		t.Errorf("wb.Flush() = %d, want 1", n)
+	}
+	for _, k := range []string{"baz", "dep"} {
+		if _, ok := c.Get(k); ok {
+			t.Errorf("cache.Get(%s) after the flush of the expired set should miss", k)
+		}
+	}
+}
+
+func TestWriteBufferFlusher(t *testing.T) {
+	c := New[string, int](0)
+	wb := NewWriteBuffer(c, 0)
+
+	exit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		wb.Flusher(time.Hour, exit)
+		close(done)
+	}()
+
+	wb.Set("foo", 1, 1, 10)
+	close(exit)
+	<-done
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Errorf("cache.Get(foo) = (%d, %v) after the Flusher exit, want (1, true)", v, ok)
+	}
+}